@@ -0,0 +1,193 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"io"
+	"os"
+	"reflect"
+)
+
+var closerType = reflect.TypeOf((*io.Closer)(nil)).Elem()
+
+// loadDst loads dst, identified by key, using load and returns the result of finishing it. If the
+// Reader is Atomic, load is passed a pointer to a copy of the value dst points to, and the copy is
+// only assigned to dst if loading succeeds (or has no value). Otherwise, any io.Closers in the copy
+// that aren't also in dst are closed, along with the files and listeners opened by envi.
+func (r readState) loadDst(dst interface{}, key string, load func(dst interface{}) error) error {
+	out := reflect.ValueOf(dst)
+	if !r.Atomic || out.Kind() != reflect.Ptr || out.IsNil() {
+		return r.finish(key, load(dst))
+	}
+
+	scratch := reflect.New(out.Elem().Type())
+	scratch.Elem().Set(deepCopy(out.Elem()))
+	err := r.collectErrors(key, load(scratch.Interface()))
+	if err == nil || IsNoValue(err) {
+		out.Elem().Set(scratch.Elem())
+		return err
+	}
+
+	for _, c := range newClosers(scratch.Elem(), out.Elem(), *r.closers) {
+		r.track(c)
+	}
+	r.closeAll()
+	return err
+}
+
+// newClosers returns the io.Closers reachable from v (see closerSet.collect) that aren't reachable
+// from prev or in known. The standard streams are never included, since envi assigns them without
+// opening them.
+func newClosers(v, prev reflect.Value, known []io.Closer) (closers []io.Closer) {
+	old := closerSet{}
+	for _, f := range []*os.File{os.Stdin, os.Stdout, os.Stderr} {
+		old.add(f)
+	}
+	if prev.IsValid() {
+		old.collect(prev, visits{})
+	}
+	for _, c := range known {
+		old.add(c)
+	}
+	cur := closerSet{}
+	cur.collect(v, visits{})
+	for _, c := range cur.list {
+		if !old.has(c) {
+			closers = append(closers, c)
+		}
+	}
+	return closers
+}
+
+// visit identifies a pointer already walked by deepCopy or closerSet.collect. The type is needed
+// since a struct and its first field share an address.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+type visits map[visit]reflect.Value
+
+// deepCopy returns a copy of v that shares no pointers, slices, or maps that envi could load into
+// with v. Only exported struct fields are copied deeply, since envi doesn't load unexported ones,
+// and interfaces are copied as-is, since envi only ever replaces them.
+func deepCopy(v reflect.Value) reflect.Value {
+	return visits{}.copy(v)
+}
+
+func (seen visits) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		// Pointers that envi assigns rather than loads into (e.g., *os.File) are kept as-is.
+		if v.IsNil() || pointerTypes[v.Type()] {
+			return v
+		}
+		k := visit{v.Pointer(), v.Type()}
+		if p, ok := seen[k]; ok {
+			return p
+		}
+		p := reflect.New(v.Type().Elem())
+		seen[k] = p
+		p.Elem().Set(seen.copy(v.Elem()))
+		return p
+	case reflect.Struct:
+		s := reflect.New(v.Type()).Elem()
+		s.Set(v)
+		for i := 0; i < s.NumField(); i++ {
+			if f := s.Field(i); f.CanSet() {
+				f.Set(seen.copy(v.Field(i)))
+			}
+		}
+		return s
+	case reflect.Array:
+		a := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			a.Index(i).Set(seen.copy(v.Index(i)))
+		}
+		return a
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(seen.copy(v.Index(i)))
+		}
+		return s
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			m.SetMapIndex(iter.Key(), seen.copy(iter.Value()))
+		}
+		return m
+	}
+	return v
+}
+
+// closerSet is a set of io.Closers, in the order they were added. Closers whose dynamic types
+// aren't comparable are never added.
+type closerSet struct {
+	set  map[io.Closer]bool
+	list []io.Closer
+}
+
+func (cs *closerSet) add(c io.Closer) {
+	if c == nil || !reflect.TypeOf(c).Comparable() || cs.has(c) {
+		return
+	}
+	if cs.set == nil {
+		cs.set = map[io.Closer]bool{}
+	}
+	cs.set[c] = true
+	cs.list = append(cs.list, c)
+}
+
+func (cs *closerSet) has(c io.Closer) bool {
+	return reflect.TypeOf(c).Comparable() && cs.set[c]
+}
+
+// collect adds the io.Closers held by pointers and interfaces reachable from v, through exported
+// struct fields, slice and array elements, and map values, to the set.
+func (cs *closerSet) collect(v reflect.Value, seen visits) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if c, ok := v.Interface().(io.Closer); ok {
+			cs.add(c)
+		}
+		cs.collect(v.Elem(), seen)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if v.Type().Implements(closerType) {
+			cs.add(v.Interface().(io.Closer))
+		}
+		k := visit{v.Pointer(), v.Type()}
+		if _, ok := seen[k]; ok || pointerTypes[v.Type()] {
+			return
+		}
+		seen[k] = v
+		cs.collect(v.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				cs.collect(v.Field(i), seen)
+			}
+		}
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			cs.collect(v.Index(i), seen)
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			cs.collect(iter.Value(), seen)
+		}
+	}
+}
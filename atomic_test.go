@@ -0,0 +1,190 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type atomicConfig struct {
+	Name   string
+	Port   int
+	DB     *atomicDB
+	Limits map[string]int
+	Hosts  []string
+}
+
+type atomicDB struct {
+	Host string
+	Pool int `envi:",default=4"`
+}
+
+func newAtomicConfig() *atomicConfig {
+	return &atomicConfig{
+		Name:   "old",
+		Port:   80,
+		DB:     &atomicDB{Host: "db0", Pool: 2},
+		Limits: map[string]int{"a": 1},
+		Hosts:  []string{"h0"},
+	}
+}
+
+func TestGetenvAtomic(t *testing.T) {
+	env := Values{
+		"test_Name":     {"new"},
+		"test_DB_Host":  {"db1"},
+		"test_Limits_a": {"2"},
+		"test_Limits_b": {"3"},
+		"test_Hosts":    {"h1", "h2"},
+	}
+	bad := Values{"test_Port": {"eighty"}}
+	for k, v := range env {
+		bad[k] = v
+	}
+
+	t.Run("Failure", func(t *testing.T) {
+		got := newAtomicConfig()
+		db, limits := got.DB, got.Limits
+		r := Reader{Source: bad, Sep: "_", Atomic: true}
+		if err := r.Getenv(got, "test"); err == nil {
+			t.Fatal("Getenv() error = nil; want an error")
+		}
+		if want := newAtomicConfig(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Getenv() dst = %#+v; want %#+v", got, want)
+		}
+		if got.DB != db || reflect.ValueOf(got.Limits).Pointer() != reflect.ValueOf(limits).Pointer() {
+			t.Error("Getenv() replaced dst pointers or maps after failing")
+		}
+	})
+
+	t.Run("FailureNotAtomic", func(t *testing.T) {
+		got := newAtomicConfig()
+		r := Reader{Source: bad, Sep: "_"}
+		if err := r.Getenv(got, "test"); err == nil {
+			t.Fatal("Getenv() error = nil; want an error")
+		}
+		if got.Name != "new" {
+			t.Errorf("Getenv() Name = %q; want partially loaded %q", got.Name, "new")
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		got := newAtomicConfig()
+		old := got.DB
+		r := Reader{Source: env, Sep: "_", Atomic: true}
+		if err := r.Getenv(got, "test"); err != nil {
+			t.Fatalf("Getenv() error = %v", err)
+		}
+		want := &atomicConfig{
+			Name:   "new",
+			Port:   80,
+			DB:     &atomicDB{Host: "db1", Pool: 4},
+			Limits: map[string]int{"a": 2, "b": 3},
+			Hosts:  []string{"h1", "h2"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Getenv() dst = %#+v; want %#+v", got, want)
+		}
+		if *old != (atomicDB{Host: "db0", Pool: 2}) {
+			t.Errorf("Getenv() modified the previous DB = %#+v", old)
+		}
+	})
+
+	t.Run("NoValue", func(t *testing.T) {
+		got := &atomicDB{Host: "db0"}
+		r := Reader{Source: Values{}, Sep: "_", Atomic: true}
+		if err := r.Getenv(got, "test"); !IsNoValue(err) {
+			t.Fatalf("Getenv() error = %v; want a no-value error", err)
+		}
+		if want := (atomicDB{Host: "db0", Pool: 4}); *got != want {
+			t.Errorf("Getenv() dst = %#+v; want %#+v", *got, want)
+		}
+	})
+}
+
+type atomicCloser struct {
+	Val    string
+	closed bool
+}
+
+func (c *atomicCloser) UnmarshalEnv(key, val string) error {
+	c.Val = val
+	return nil
+}
+
+func (c *atomicCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestGetenvAtomicCloses(t *testing.T) {
+	type config struct {
+		Kept   *atomicCloser
+		Added  *atomicCloser
+		Nested struct{ C *atomicCloser }
+		Port   int
+	}
+
+	kept := &atomicCloser{Val: "kept"}
+	got := config{Kept: kept}
+	r := Reader{Source: Values{
+		"test_Added":    {"added"},
+		"test_Nested_C": {"nested"},
+		"test_Port":     {"eighty"},
+	}, Sep: "_", AllErrors: true, Atomic: true}
+
+	var scratch config
+	rs := r.readState()
+	err := rs.loadDst(&got, "test", func(dst interface{}) error {
+		err := rs.loadFromEnv(dst, "test")
+		scratch = *dst.(*config)
+		return err
+	})
+	if err == nil {
+		t.Fatal("Getenv() error = nil; want an error")
+	}
+	if got.Added != nil || got.Nested.C != nil {
+		t.Errorf("Getenv() assigned dst = %#+v after failing", got)
+	}
+	if kept.closed {
+		t.Error("Getenv() closed a value already in dst")
+	}
+	if scratch.Added == nil || !scratch.Added.closed {
+		t.Errorf("Getenv() Added = %#+v; want closed", scratch.Added)
+	}
+	if scratch.Nested.C == nil || !scratch.Nested.C.closed {
+		t.Errorf("Getenv() Nested.C = %#+v; want closed", scratch.Nested.C)
+	}
+}
+
+func TestGetenvAtomicStdStreams(t *testing.T) {
+	type config struct {
+		Out  io.Writer
+		Log  *os.File
+		Port int
+	}
+
+	log, err := os.CreateTemp(t.TempDir(), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	got := config{Log: log}
+	r := Reader{Source: Values{"test_Out": {"stderr"}, "test_Port": {"x"}}, Sep: "_", Atomic: true}
+	if err := r.Getenv(&got, "test"); err == nil {
+		t.Fatal("Getenv() error = nil; want an error")
+	}
+	if got.Out != nil || got.Log != log {
+		t.Errorf("Getenv() dst = %#+v; want unchanged", got)
+	}
+	for _, f := range []*os.File{os.Stderr, log} {
+		if _, err := f.Write(nil); err != nil {
+			t.Errorf("%s closed by a failed load: %v", f.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import "sort"
+
+// Layer is a named environment variable source in a Chain.
+type Layer struct {
+	Name string
+	Env  Env
+}
+
+// Layered is implemented by sources made of named layers, such as Chain. When a Reader's Source is
+// Layered, values are split according to the layer holding them and traces name that layer, even
+// if the layer is itself Layered (e.g., a Chain nested in another Chain).
+type Layered interface {
+	Env
+	// Find returns the index, in Layers, of the layer that supplies the value of key. If no layer
+	// holds key, it returns -1 and a no-value error.
+	Find(key string) (int, error)
+	// Layers returns the layers of the source, from highest to lowest priority.
+	Layers() []Layer
+}
+
+// Chain is an Env that consults each of its layers in order, from highest to lowest priority, and
+// returns the value of the first layer holding a key. A layer is only skipped if it returns
+// a no-value error -- any other error is returned as-is.
+//
+// Chain is also a Multienv and a KeyLister. When used as a Reader's Source, values from layers that
+// are not Multienvs are still split using the Reader's Split.
+//
+// For example, to prefer the process environment over a local dotenv file over defaults:
+//
+//   envi.Chain{
+//       {Name: "env", Env: envi.OSEnv},
+//       {Name: ".env.local", Env: local},
+//       {Name: "defaults", Env: defaults},
+//   }
+type Chain []Layer
+
+var (
+	_ Multienv  = Chain(nil)
+	_ KeyLister = Chain(nil)
+	_ Layered   = Chain(nil)
+)
+
+// Layers returns the layers of the Chain. This implements Layered.
+func (c Chain) Layers() []Layer {
+	return c
+}
+
+// Find returns the index of the layer that supplies the value of key. If no layer holds key, it
+// returns -1 and a no-value error.
+func (c Chain) Find(key string) (int, error) {
+	for i, l := range c {
+		if _, err := l.Env.Getenv(key); err == nil {
+			return i, nil
+		} else if !IsNoValue(err) {
+			return -1, err
+		}
+	}
+	return -1, NoValueError(key)
+}
+
+// Getenv returns the value of key held by the first layer that has one. This implements Env.
+func (c Chain) Getenv(key string) (string, error) {
+	for _, l := range c {
+		if v, err := l.Env.Getenv(key); err == nil || !IsNoValue(err) {
+			return v, err
+		}
+	}
+	return "", NoValueError(key)
+}
+
+// GetenvAll returns the values of key held by the first layer that has any. Layers that are not
+// Multienvs are treated as holding a single value. This implements Multienv.
+func (c Chain) GetenvAll(key string) ([]string, error) {
+	for _, l := range c {
+		var (
+			vals []string
+			err  error
+		)
+		if me, ok := l.Env.(Multienv); ok {
+			vals, err = me.GetenvAll(key)
+		} else if v, verr := l.Env.Getenv(key); verr == nil {
+			vals = []string{v}
+		} else {
+			err = verr
+		}
+
+		if err == nil || !IsNoValue(err) {
+			return vals, err
+		}
+	}
+	return nil, NoValueError(key)
+}
+
+// Keys returns the sorted union of keys, beginning with prefix, held by all layers. If any layer is
+// not a KeyLister, it returns ErrKeysUnavailable, since the keys of that layer are unknown. This
+// implements KeyLister.
+func (c Chain) Keys(prefix string) ([]string, error) {
+	seen := make(map[string]bool)
+	keys := []string{}
+	for _, l := range c {
+		kl, ok := l.Env.(KeyLister)
+		if !ok {
+			return nil, ErrKeysUnavailable
+		}
+
+		layerKeys, err := kl.Keys(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range layerKeys {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// findLayer returns the layer of src that supplies the value of key, descending into layers that
+// are themselves Layered. The names of nested layers are joined with a slash (e.g., "config/local").
+// If src is not Layered or no layer holds key, ok is false.
+func findLayer(src Env, key string) (l Layer, ok bool) {
+	for {
+		ls, layered := src.(Layered)
+		if !layered {
+			return l, ok
+		}
+		i, err := ls.Find(key)
+		if err != nil {
+			return l, ok
+		}
+		inner := ls.Layers()[i]
+		if ok && l.Name != "" {
+			if inner.Name == "" {
+				inner.Name = l.Name
+			} else {
+				inner.Name = l.Name + "/" + inner.Name
+			}
+		}
+		l, ok, src = inner, true, inner.Env
+	}
+}
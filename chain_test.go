@@ -0,0 +1,115 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	errBroken := errors.New("broken")
+	chain := Chain{
+		{Name: "high", Env: singleValues{"A": "high", "SPLIT": "1,2"}},
+		{Name: "broken", Env: EnvFunc(func(key string) (string, error) {
+			if key == "BROKEN" {
+				return "", errBroken
+			}
+			return "", NoValueError(key)
+		})},
+		{Name: "low", Env: Values{"A": {"low"}, "B": {"low", "lower"}, "BROKEN": {"ok"}}},
+	}
+
+	cases := []struct {
+		key   string
+		val   string
+		vals  []string
+		layer int
+		err   error
+	}{
+		{"A", "high", []string{"high"}, 0, nil},
+		{"B", "low", []string{"low", "lower"}, 2, nil},
+		{"BROKEN", "", nil, -1, errBroken},
+	}
+
+	for _, c := range cases {
+		if val, err := chain.Getenv(c.key); val != c.val || err != c.err {
+			t.Errorf("Getenv(%q) = %q, %v; want %q, %v", c.key, val, err, c.val, c.err)
+		}
+		if vals, err := chain.GetenvAll(c.key); !reflect.DeepEqual(vals, c.vals) || err != c.err {
+			t.Errorf("GetenvAll(%q) = %q, %v; want %q, %v", c.key, vals, err, c.vals, c.err)
+		}
+		if layer, err := chain.Find(c.key); layer != c.layer || err != c.err {
+			t.Errorf("Find(%q) = %d, %v; want %d, %v", c.key, layer, err, c.layer, c.err)
+		}
+	}
+
+	if _, err := chain.Getenv("C"); !IsNoValue(err) {
+		t.Errorf("Getenv(%q) err = %v; want IsNoValue(err)", "C", err)
+	}
+	if _, err := chain.GetenvAll("C"); !IsNoValue(err) {
+		t.Errorf("GetenvAll(%q) err = %v; want IsNoValue(err)", "C", err)
+	}
+	if layer, err := chain.Find("C"); layer != -1 || !IsNoValue(err) {
+		t.Errorf("Find(%q) = %d, %v; want -1, IsNoValue(err)", "C", layer, err)
+	}
+
+	// Only the Multienv layer's values are kept as-is. The rest are split.
+	r := Reader{Source: chain, Split: StringSplitter(",")}
+	var split, multi []string
+	if err := r.Getenv(&split, "SPLIT"); err != nil || !reflect.DeepEqual(split, []string{"1", "2"}) {
+		t.Errorf("Getenv(SPLIT) = %q, %v; want [1 2], nil", split, err)
+	}
+	if err := r.Getenv(&multi, "B"); err != nil || !reflect.DeepEqual(multi, []string{"low", "lower"}) {
+		t.Errorf("Getenv(B) = %q, %v; want [low lower], nil", multi, err)
+	}
+}
+
+func TestChainKeys(t *testing.T) {
+	chain := Chain{
+		{Name: "a", Env: Values{"X_1": nil, "X_2": nil, "Y": nil}},
+		{Name: "b", Env: Values{"X_2": nil, "X_3": nil}},
+	}
+
+	keys, err := chain.Keys("X_")
+	if err != nil {
+		t.Fatalf("Keys() err = %v; want nil", err)
+	}
+	if want := []string{"X_1", "X_2", "X_3"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys() = %q; want %q", keys, want)
+	}
+
+	chain = append(chain, Layer{Name: "func", Env: EnvFunc(OSEnv.Getenv)})
+	if _, err := chain.Keys("X_"); err != ErrKeysUnavailable {
+		t.Errorf("Keys() err = %v; want %v", err, ErrKeysUnavailable)
+	}
+}
+
+func TestChainNested(t *testing.T) {
+	inner := Chain{
+		{Name: "single", Env: singleValues{"SPLIT": "1,2"}},
+		{Name: "multi", Env: Values{"MULTI": {"a,b", "c"}}},
+	}
+	outer := Chain{{Name: "outer", Env: &inner}}
+
+	if l, ok := findLayer(&outer, "MULTI"); !ok || l.Name != "outer/multi" {
+		t.Errorf("findLayer(MULTI) = %q, %t; want %q, true", l.Name, ok, "outer/multi")
+	}
+	if _, ok := findLayer(outer, "NONE"); ok {
+		t.Errorf("findLayer(NONE) ok = true; want false")
+	}
+
+	// Values are split according to the innermost layer holding them.
+	for _, src := range []Env{outer, &outer} {
+		r := Reader{Source: src, Split: StringSplitter(",")}
+		var split, multi []string
+		if err := r.Getenv(&split, "SPLIT"); err != nil || !reflect.DeepEqual(split, []string{"1", "2"}) {
+			t.Errorf("Getenv(SPLIT) = %q, %v; want [1 2], nil", split, err)
+		}
+		if err := r.Getenv(&multi, "MULTI"); err != nil || !reflect.DeepEqual(multi, []string{"a,b", "c"}) {
+			t.Errorf("Getenv(MULTI) = %q, %v; want [a,b c], nil", multi, err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"cmp"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ConstraintError is the error of a struct field whose value violates one of its constraint flags
+// (e.g., min=1), or whose constraint flag is itself invalid. It is returned inside a *KeyError
+// holding the field's environment variable.
+type ConstraintError struct {
+	Constraint string // The constraint flag, as written in the field tag (e.g., "min=1")
+	Err        error  // The reason the constraint is invalid, if it is invalid
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Err != nil {
+		return "invalid constraint " + strconv.Quote(e.Constraint) + errstr(e.Err)
+	}
+	return "value violates constraint " + strconv.Quote(e.Constraint)
+}
+
+// Unwrap returns the reason the constraint is invalid, if any.
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+var errConstraintType = errors.New("not supported by the field's type")
+
+// constraintRegexps caches the regexps of match flags, keyed by pattern.
+var constraintRegexps sync.Map
+
+func constraintRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := constraintRegexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	constraintRegexps.Store(pattern, re)
+	return re, nil
+}
+
+// checkConstraints returns an error if v, the value loaded for the struct field identified by key,
+// violates the constraint flags of the readState.
+func (r readState) checkConstraints(v reflect.Value, key string) error {
+	flags := &r.flags
+	v = derefValue(v)
+	if flags.nonzero != "" && (!v.IsValid() || isZeroValue(v)) {
+		return constraintError(key, flags.nonzero, nil)
+	} else if !v.IsValid() {
+		return nil
+	}
+	for _, c := range []string{flags.minlen, flags.maxlen} {
+		if c == "" {
+			continue
+		}
+		if ok, err := checkLen(v, c); !ok {
+			return constraintError(key, c, err)
+		}
+	}
+	for _, c := range []string{flags.min, flags.max, flags.oneof, flags.match} {
+		if c == "" {
+			continue
+		}
+		for _, elem := range r.constraintElems(v) {
+			if ok, err := r.checkElem(elem, c, key); !ok {
+				return constraintError(key, c, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isZeroValue returns whether v is the zero value of its type or an empty slice or map.
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// constraintError returns the error of key violating the constraint c, where err is the reason c
+// is invalid, if it is.
+func constraintError(key, c string, err error) error {
+	return &KeyError{Key: key, Err: &ConstraintError{Constraint: c, Err: err}}
+}
+
+// derefValue returns the value v points to, through any number of pointers. It returns an invalid
+// reflect.Value if any pointer is nil.
+func derefValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && v.Kind() == reflect.Ptr && !pointerTypes[v.Type().Elem()] {
+		v = v.Elem()
+	}
+	if v.IsValid() && v.Kind() == reflect.Ptr && v.IsNil() {
+		return reflect.Value{}
+	}
+	return v
+}
+
+// constraintElems returns the values that the min, max, oneof, and match constraints apply to: the
+// elements of slices and arrays, the values of maps, and v itself otherwise. Byte slices and types
+// loaded from a single value (e.g., net.IP and types with Decoders) are not treated as slices.
+func (r readState) constraintElems(v reflect.Value) (elems []reflect.Value) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 || r.isSplitType(v.Type()) {
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			elems = append(elems, v.Index(i))
+		}
+		return elems
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			elems = append(elems, iter.Value())
+		}
+		return elems
+	}
+	return []reflect.Value{v}
+}
+
+// checkLen returns whether v satisfies the minlen or maxlen constraint c, where lengths of strings
+// are in characters. If c is invalid, it returns the reason.
+func checkLen(v reflect.Value, c string) (ok bool, err error) {
+	name, param, _ := strings.Cut(c, "=")
+	bound, err := strconv.Atoi(param)
+	if err != nil {
+		return false, err
+	}
+
+	var n int
+	switch v.Kind() {
+	case reflect.String:
+		n = utf8.RuneCountInString(v.String())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n = v.Len()
+	default:
+		return false, errConstraintType
+	}
+	if name == "minlen" {
+		return n >= bound, nil
+	}
+	return n <= bound, nil
+}
+
+// checkElem returns whether v satisfies the min, max, oneof, or match constraint c of the field
+// identified by key. Bounds and options are loaded into values of v's type, using the field's
+// flags (e.g., unit=bytes allows min=1MiB). If c is invalid, it returns the reason.
+func (r readState) checkElem(v reflect.Value, c, key string) (ok bool, err error) {
+	if v = derefValue(v); !v.IsValid() {
+		return true, nil
+	}
+	name, param, _ := strings.Cut(c, "=")
+
+	switch name {
+	case "match":
+		re, err := constraintRegexp(param)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			return re.MatchString(v.String()), nil
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			return re.Match(v.Bytes()), nil
+		}
+		return false, errConstraintType
+	case "oneof":
+		for _, opt := range strings.Split(param, "|") {
+			ov, err := r.constraintValue(v.Type(), opt, key)
+			if err != nil {
+				return false, err
+			}
+			// Options are equal the same way as bounds (e.g., times at the same instant), where the
+			// type is ordered.
+			if order, err := compareValues(v, ov); err == nil {
+				if order == 0 {
+					return true, nil
+				}
+			} else if reflect.DeepEqual(v.Interface(), ov.Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	bound, err := r.constraintValue(v.Type(), param, key)
+	if err != nil {
+		return false, err
+	}
+	order, err := compareValues(v, bound)
+	if err != nil {
+		return false, err
+	}
+	if name == "min" {
+		return order >= 0, nil
+	}
+	return order <= 0, nil
+}
+
+// constraintValue loads the constraint parameter param into a new value of type t. Parameters
+// can't be loaded into resource types, since they'd open resources that are never closed.
+func (r readState) constraintValue(t reflect.Type, param, key string) (reflect.Value, error) {
+	v := reflect.New(t)
+	if isResourceType(t) {
+		return v, errConstraintType
+	}
+	if err := r.asLiteral().load(v.Interface(), param, key); err != nil {
+		if IsNoValue(err) {
+			err = ErrNoValue
+		}
+		return v, err
+	}
+	return v.Elem(), nil
+}
+
+// compareValues returns -1, 0, or 1 if a is less than, equal to, or greater than b, which must be
+// numbers or times of the same type. NaN is equal to itself and less than other numbers.
+func compareValues(a, b reflect.Value) (int, error) {
+	if t, ok := a.Interface().(time.Time); ok {
+		return t.Compare(b.Interface().(time.Time)), nil
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float()), nil
+	}
+	return 0, errConstraintType
+}
@@ -0,0 +1,261 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type constraintConfig struct {
+	Port    int            `envi:",min=1,max=65535"`
+	Ratio   float64        `envi:",min=0,max=1"`
+	Size    int64          `envi:",unit=bytes,max=1MiB"`
+	Timeout time.Duration  `envi:",min=1s,max=1m"`
+	Since   time.Time      `envi:",layout=DateOnly,min=2020-01-01"`
+	Level   string         `envi:",oneof=debug|info|error"`
+	Codes   []int          `envi:",oneof=200|404"`
+	Name    string         `envi:",match=^[a-z]+$,minlen=2,maxlen=4"`
+	Hosts   []string       `envi:",minlen=1,maxlen=2,match=\\.example\\.com$"`
+	Token   string         `envi:",nonzero"`
+	Retries *int           `envi:",nonzero"`
+	Weights map[string]int `envi:",max=10"`
+	Mode    string         `envi:",default=slow,oneof=fast|slow"`
+}
+
+func TestGetenvConstraints(t *testing.T) {
+	valid := Values{
+		"test_Port":      {"8080"},
+		"test_Ratio":     {"0.5"},
+		"test_Size":      {"512KiB"},
+		"test_Timeout":   {"30s"},
+		"test_Since":     {"2024-06-01"},
+		"test_Level":     {"info"},
+		"test_Codes":     {"200", "404"},
+		"test_Name":      {"abc"},
+		"test_Hosts":     {"a.example.com"},
+		"test_Token":     {"secret"},
+		"test_Retries":   {"3"},
+		"test_Weights_a": {"10"},
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		var got constraintConfig
+		r := Reader{Source: valid, Sep: "_"}
+		if err := r.Getenv(&got, "test"); err != nil {
+			t.Fatalf("Getenv() error = %v", err)
+		}
+		if got.Mode != "slow" {
+			t.Errorf("Getenv() Mode = %q; want %q", got.Mode, "slow")
+		}
+	})
+
+	invalid := []struct {
+		key        string
+		vals       []string
+		constraint string
+	}{
+		{"test_Port", []string{"0"}, "min=1"},
+		{"test_Port", []string{"70000"}, "max=65535"},
+		{"test_Ratio", []string{"1.5"}, "max=1"},
+		{"test_Size", []string{"2MiB"}, "max=1MiB"},
+		{"test_Timeout", []string{"500ms"}, "min=1s"},
+		{"test_Since", []string{"2019-12-31"}, "min=2020-01-01"},
+		{"test_Level", []string{"warn"}, "oneof=debug|info|error"},
+		{"test_Codes", []string{"200", "500"}, "oneof=200|404"},
+		{"test_Name", []string{"ABC"}, "match=^[a-z]+$"},
+		{"test_Name", []string{"a"}, "minlen=2"},
+		{"test_Name", []string{"abcde"}, "maxlen=4"},
+		{"test_Hosts", []string{"a.example.com", "b.example.org"}, "match=\\.example\\.com$"},
+		{"test_Hosts", []string{"a.example.com", "b.example.com", "c.example.com"}, "maxlen=2"},
+		{"test_Token", []string{""}, "nonzero"},
+		{"test_Retries", []string{"0"}, "nonzero"},
+		{"test_Weights_b", []string{"11"}, "max=10"},
+		{"test_Mode", []string{"medium"}, "oneof=fast|slow"},
+	}
+
+	for _, c := range invalid {
+		c := c
+		t.Run(c.key+"="+strings.Join(c.vals, " "), func(t *testing.T) {
+			env := Values{}
+			for k, v := range valid {
+				env[k] = v
+			}
+			env[c.key] = c.vals
+
+			var got constraintConfig
+			r := Reader{Source: env, Sep: "_"}
+			err := r.Getenv(&got, "test")
+
+			var ke *KeyError
+			var ce *ConstraintError
+			if !errors.As(err, &ke) || !errors.As(err, &ce) {
+				t.Fatalf("Getenv() error = %v; want a *KeyError holding a *ConstraintError", err)
+			}
+			if want := strings.TrimSuffix(c.key, "_b"); ke.Key != want {
+				t.Errorf("Getenv() error key = %q; want %q", ke.Key, want)
+			}
+			if ce.Constraint != c.constraint || ce.Err != nil {
+				t.Errorf("Getenv() error = %v; want a violation of %q", err, c.constraint)
+			}
+		})
+	}
+}
+
+func TestGetenvConstraintsOneofOrdered(t *testing.T) {
+	type config struct {
+		At    time.Time `envi:",oneof=2024-01-01T00:00:00Z|2025-01-01T00:00:00Z"`
+		Ratio float64   `envi:",oneof=NaN|1"`
+	}
+
+	r := Reader{Source: Values{
+		"test_At":    {"2024-01-01T01:00:00+01:00"},
+		"test_Ratio": {"NaN"},
+	}, Sep: "_"}
+	var got config
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+
+	r.Source = Values{"test_At": {"2024-01-01T00:00:00+01:00"}}
+	var ce *ConstraintError
+	if err := r.Getenv(&got, "test"); !errors.As(err, &ce) {
+		t.Errorf("Getenv() error = %v; want a *ConstraintError", err)
+	}
+}
+
+type constraintPair []int
+
+func TestGetenvConstraintsDecoders(t *testing.T) {
+	type config struct {
+		Pair constraintPair `envi:",oneof=1:2|3:4"`
+	}
+
+	r := &Reader{Source: Values{"test_Pair": {"3:4"}}, Sep: "_"}
+	Register(r, func(key, val string) (p constraintPair, err error) {
+		for _, s := range strings.Split(val, ":") {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, n)
+		}
+		return p, nil
+	})
+
+	var got config
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+	r.Source = Values{"test_Pair": {"1:4"}}
+	var ce *ConstraintError
+	if err := r.Getenv(&got, "test"); !errors.As(err, &ce) || ce.Err != nil {
+		t.Errorf("Getenv() error = %v; want a constraint violation", err)
+	}
+}
+
+func TestGetenvConstraintsInvalid(t *testing.T) {
+	type config struct {
+		Name  string `envi:",min=1"`
+		Port  int    `envi:",min=one"`
+		Match string `envi:",match=["`
+		Len   int    `envi:",minlen=1"`
+	}
+
+	r := Reader{Source: Values{
+		"test_Name":  {"a"},
+		"test_Port":  {"1"},
+		"test_Match": {"a"},
+		"test_Len":   {"1"},
+	}, Sep: "_", AllErrors: true}
+
+	var got config
+	err := r.Getenv(&got, "test")
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("Getenv() error = %v; want 4 errors", err)
+	}
+	for _, ke := range errs {
+		var ce *ConstraintError
+		if !errors.As(ke, &ce) || ce.Err == nil {
+			t.Errorf("Getenv() error = %v; want an invalid constraint error", ke)
+		}
+	}
+	if got != (config{}) {
+		t.Errorf("Getenv() dst = %#+v; want no fields assigned", got)
+	}
+}
+
+func TestGetenvConstraintsResources(t *testing.T) {
+	const bound = "envi-constraint-bound"
+	type config struct {
+		Out    io.Writer    `envi:",oneof=envi-constraint-bound"`
+		Listen net.Listener `envi:",min=tcp://127.0.0.1:0"`
+	}
+
+	r := Reader{Source: Values{
+		"test_Out":    {"stderr"},
+		"test_Listen": {"tcp://127.0.0.1:0"},
+	}, Sep: "_", AllErrors: true}
+	err := r.Getenv(new(config), "test")
+	defer os.Remove(bound)
+
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Getenv() error = %v; want 2 errors", err)
+	}
+	for _, e := range errs {
+		var ce *ConstraintError
+		if !errors.As(e, &ce) || ce.Err != errConstraintType {
+			t.Errorf("Getenv() error = %v; want %v", e, errConstraintType)
+		}
+	}
+	if _, err := os.Stat(bound); err == nil {
+		t.Errorf("Getenv() created %s loading a constraint", bound)
+	}
+}
+
+func TestGetenvConstraintsQuiet(t *testing.T) {
+	type config struct {
+		Port  int `envi:",min=1,quiet"`
+		Other int
+	}
+
+	got := config{Port: 80}
+	r := Reader{Source: Values{"test_Port": {"0"}, "test_Other": {"1"}}, Sep: "_"}
+	trace, err := r.GetenvTrace(&got, "test")
+	if err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+	if want := (config{Port: 80, Other: 1}); got != want {
+		t.Errorf("Getenv() dst = %#+v; want %#+v", got, want)
+	}
+	if len(trace) == 0 || !trace[0].Quiet {
+		t.Errorf("Getenv() trace = %v; want Port marked quiet", trace)
+	}
+}
+
+func TestGetenvConstraintsQuietUnset(t *testing.T) {
+	type inner struct {
+		Port int `envi:",min=1,quiet"`
+	}
+	type config struct {
+		Inner *inner
+	}
+
+	var got config
+	r := Reader{Source: Values{"test_Inner_Port": {"0"}}, Sep: "_"}
+	if err := r.Getenv(&got, "test"); !IsNoValue(err) {
+		t.Errorf("Getenv() error = %v; want a no-value error", err)
+	}
+	if got.Inner != nil {
+		t.Errorf("Getenv() Inner = %#+v; want nil", got.Inner)
+	}
+}
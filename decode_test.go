@@ -8,7 +8,10 @@ package envi
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -120,6 +123,7 @@ func TestPermittedNoValue(t *testing.T) {
 		{new(struct{ x int }), true},     // Pointer to struct
 		{new([]int), true},               // Pointer to slice
 		{new([]byte), true},              // Pointer to slice
+		{new(map[string]int), true},      // Pointer to map
 		{new(unmarshalerDummyPtr), true}, // Pointer receiver
 		{unmarshalerDummy(0), true},      // Value receiver (useless, but checks iface)
 	}
@@ -532,6 +536,459 @@ func TestGetenvStructs(t *testing.T) {
 	})
 }
 
+type mapBackend struct {
+	Host string `envi:"HOST"`
+	Port int    `envi:"PORT"`
+}
+
+type mapConfig struct {
+	Limits   map[string]int         `envi:"LIMITS"`
+	Backends map[string]*mapBackend `envi:"BACKENDS"`
+}
+
+func TestGetenvMaps(t *testing.T) {
+	testGetenv(t, []getenvTest{
+		// Scalar values use the rest of the key as the map key
+		{dst: func() interface{} { return new(map[string]int) },
+			want: map[string]int{"a": 1, "b_c": 2},
+			in: Values{
+				"test_a":   {"1"},
+				"test_b_c": {"2"},
+				"other_d":  {"3"},
+			},
+		},
+
+		// Non-string keys
+		{dst: func() interface{} { return new(map[int]string) },
+			want: map[int]string{1: "one", 16: "sixteen"},
+			in: Values{
+				"test_1":    {"one"},
+				"test_0x10": {"sixteen"},
+			},
+		},
+
+		// Invalid keys
+		{dst: func() interface{} { return new(map[int]string) },
+			bad:  true,
+			want: map[int]string(nil),
+			in: Values{
+				"test_one": {"1"},
+			},
+		},
+
+		// Invalid values
+		{dst: func() interface{} { return new(map[string]int) },
+			bad:  true,
+			want: map[string]int(nil),
+			in: Values{
+				"test_one": {"one"},
+			},
+		},
+
+		// Keep existing entries
+		{dst: func() interface{} { return &map[string]int{"a": 1, "b": 2} },
+			want: map[string]int{"a": 1, "b": 3, "c": 4},
+			in: Values{
+				"test_b": {"3"},
+				"test_c": {"4"},
+			},
+		},
+
+		// Struct values end their key at the separator
+		{dst: func() interface{} { return new(mapConfig) },
+			want: &mapConfig{
+				Limits: map[string]int{"tenant_a": 10, "tenant_b": 20},
+				Backends: map[string]*mapBackend{
+					"primary":   {Host: "db1", Port: 5432},
+					"secondary": {Host: "db2"},
+				},
+			},
+			in: Values{
+				"test_LIMITS_tenant_a":         {"10"},
+				"test_LIMITS_tenant_b":         {"20"},
+				"test_BACKENDS_primary_HOST":   {"db1"},
+				"test_BACKENDS_primary_PORT":   {"5432"},
+				"test_BACKENDS_secondary_HOST": {"db2"},
+				"test_BACKENDS_unknown_NAME":   {"ignored"},
+			},
+		},
+
+		// Slice values end their key at the separator, for numbered elements
+		{dst: func() interface{} { return new(map[string][]int) },
+			want: map[string][]int{"a": {1, 2}, "b": {3, 4}},
+			in: Values{
+				"test_a_1": {"1"},
+				"test_a_2": {"2"},
+				"test_b":   {"3", "4"},
+			},
+		},
+		{dst: func() interface{} { return new(map[string][]mapBackend) },
+			want: map[string][]mapBackend{"a": {{Host: "db1"}, {Host: "db2", Port: 5432}}},
+			in: Values{
+				"test_a_1_HOST": {"db1"},
+				"test_a_2_HOST": {"db2"},
+				"test_a_2_PORT": {"5432"},
+			},
+		},
+
+		// Load none
+		{dst: func() interface{} { return new(map[string]int) },
+			want:  map[string]int(nil),
+			empty: true,
+			in:    Values{},
+		},
+
+		// Sources that can't list keys
+		{dst: func() interface{} { return new(map[string]int) },
+			bad:  true,
+			want: map[string]int(nil),
+			in:   envone("1"),
+		},
+	})
+}
+
+// countingValues counts lookups made against its Values.
+type countingValues struct {
+	Values
+	lookups int
+}
+
+func (c *countingValues) Getenv(key string) (string, error) {
+	c.lookups++
+	return c.Values.Getenv(key)
+}
+
+func (c *countingValues) GetenvAll(key string) ([]string, error) {
+	c.lookups++
+	return c.Values.GetenvAll(key)
+}
+
+func TestGetenvSliceSeqKeys(t *testing.T) {
+	env := &countingValues{Values: Values{
+		"test_1_Def": {"1"},
+		"test_2_Def": {"2"},
+		"test_4_Def": {"4"}, // Not consecutive, so never looked up
+	}}
+	r := Reader{Source: env, Sep: "_"}
+
+	var got []testData
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() err = %v; want nil", err)
+	}
+
+	if want := []testData{{Def: 1}, {Def: 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Getenv() = %#v; want %#v", got, want)
+	}
+
+	// Without a KeyLister, this would probe through test_1000.
+	const maxLookups = 50
+	if env.lookups > maxLookups {
+		t.Errorf("Getenv() made %d lookups; want at most %d", env.lookups, maxLookups)
+	}
+}
+
+type defaultChild struct {
+	Port int `envi:"PORT,default=5432"`
+}
+
+type defaultData struct {
+	Port  int           `envi:"PORT,default=8080"`
+	Name  string        `envi:"NAME,default=envi"`
+	Hosts []string      `envi:"HOSTS,default=a\\,b\\,c"`
+	Child defaultChild  `envi:"CHILD"`
+	Ptr   *defaultChild `envi:"PTR"`
+}
+
+func TestGetenvDefaults(t *testing.T) {
+	defaults := defaultData{
+		Port:  8080,
+		Name:  "envi",
+		Hosts: []string{"a", "b", "c"},
+		Child: defaultChild{Port: 5432},
+	}
+
+	testGetenv(t, []getenvTest{
+		// Only defaults
+		{dst: func() interface{} { return new(defaultData) },
+			empty: true,
+			want:  &defaults,
+			in:    Values{},
+		},
+
+		// Environment takes precedence, empty strings are kept, and empty numbers are defaulted
+		{dst: func() interface{} { return new(defaultData) },
+			want: &defaultData{
+				Port:  8080,
+				Name:  "",
+				Hosts: []string{"d"},
+				Child: defaultChild{Port: 5432},
+				Ptr:   &defaultChild{Port: 1234},
+			},
+			in: Values{
+				"test_PORT":     {""},
+				"test_NAME":     {""},
+				"test_HOSTS":    {"d"},
+				"test_PTR_PORT": {"1234"},
+			},
+		},
+
+		// Existing pointers receive defaults
+		{dst: func() interface{} { return &defaultData{Ptr: &defaultChild{Port: 1}} },
+			empty: true,
+			want: &defaultData{
+				Port:  8080,
+				Name:  "envi",
+				Hosts: []string{"a", "b", "c"},
+				Child: defaultChild{Port: 5432},
+				Ptr:   &defaultChild{Port: 5432},
+			},
+			in: Values{},
+		},
+
+		// Invalid defaults
+		{dst: func() interface{} {
+			return new(struct {
+				Port int `envi:"PORT,default=port"`
+			})
+		},
+			bad: true,
+			in:  Values{},
+		},
+	})
+}
+
+func TestDefaultEmpty(t *testing.T) {
+	var (
+		r   = Reader{Source: Values{"test_NAME": {""}}, Sep: "_", DefaultEmpty: true}
+		got defaultData
+	)
+	if err := r.Getenv(&got, "test"); !IsNoValue(err) {
+		t.Fatalf("Getenv() err = %v; want IsNoValue(err)", err)
+	}
+	if want := "envi"; got.Name != want {
+		t.Errorf("Getenv() Name = %q; want %q", got.Name, want)
+	}
+}
+
+type requiredChild struct {
+	User     string `envi:"USER"`
+	Password string `envi:"PASSWORD,required"`
+}
+
+type requiredData struct {
+	Host  string          `envi:"HOST,required"`
+	Port  int             `envi:"PORT,required,default=5432"`
+	DB    requiredChild   `envi:"DB"`
+	Peers []requiredChild `envi:"PEERS"`
+}
+
+func TestGetenvRequired(t *testing.T) {
+	cases := []struct {
+		name string
+		in   Values
+		want []string
+	}{
+		{"Unset", Values{}, []string{"test_HOST", "test_DB_PASSWORD"}},
+		{"Partial", Values{"test_DB_PASSWORD": {"secret"}}, []string{"test_HOST"}},
+		{"Empty", Values{"test_HOST": {""}, "test_DB_PASSWORD": {"secret"}}, nil},
+		{"Elements",
+			Values{
+				"test_HOST":             {"localhost"},
+				"test_DB_PASSWORD":      {"secret"},
+				"test_PEERS_1_USER":     {"a"},
+				"test_PEERS_2_USER":     {"b"},
+				"test_PEERS_2_PASSWORD": {"secret"},
+			},
+			[]string{"test_PEERS_1_PASSWORD"},
+		},
+		{"Complete", Values{"test_HOST": {"localhost"}, "test_DB_PASSWORD": {"secret"}}, nil},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				r   = Reader{Source: c.in, Sep: "_"}
+				dst requiredData
+				err = r.Getenv(&dst, "test")
+			)
+
+			merr, _ := err.(*MissingError)
+			if c.want == nil {
+				if err != nil {
+					t.Fatalf("Getenv() err = %v; want nil", err)
+				}
+				return
+			} else if merr == nil {
+				t.Fatalf("Getenv() err = %v; want *MissingError", err)
+			}
+
+			if !reflect.DeepEqual(merr.Keys, c.want) {
+				t.Errorf("Getenv() missing = %q; want %q", merr.Keys, c.want)
+			}
+			if IsNoValue(err) {
+				t.Errorf("IsNoValue(%v) = true; want false", err)
+			}
+			if !errors.Is(err, ErrRequired) {
+				t.Errorf("errors.Is(%v, ErrRequired) = false; want true", err)
+			}
+		})
+	}
+}
+
+func TestGetenvAllErrors(t *testing.T) {
+	var (
+		env = Values{
+			"test_Config_HOST":             {"localhost"},
+			"test_Config_PORT":             {"port"},
+			"test_Config_DB_USER":          {"user"},
+			"test_Config_PEERS_1_USER":     {"a"},
+			"test_Config_PEERS_1_PASSWORD": {"secret"},
+			"test_Config_PEERS_2_USER":     {"b"},
+		}
+		dst = struct {
+			Config  requiredData  `envi:""`
+			Timeout time.Duration `envi:"TIMEOUT"`
+		}{}
+		r = Reader{Source: env, Sep: "_", AllErrors: true}
+	)
+	env.Set("test_TIMEOUT", "forever")
+
+	err := r.Getenv(&dst, "test")
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Getenv() err = %v; want Errors", err)
+	}
+
+	type failure struct{ Key, Field string }
+	var got []failure
+	for _, e := range errs {
+		got = append(got, failure{e.Key, e.Field})
+	}
+	// Fields are loaded (and so fail) before nested structs.
+	want := []failure{
+		{"test_TIMEOUT", "Timeout"},
+		{"test_Config_PORT", "Config.Port"},
+		{"test_Config_PEERS_2_PASSWORD", "Config.Peers[1].Password"},
+		{"test_Config_DB_PASSWORD", "Config.DB.Password"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Getenv() errs = %+v; want %+v", got, want)
+	}
+
+	// Valid fields are still loaded
+	if want := "localhost"; dst.Config.Host != want {
+		t.Errorf("Getenv() Host = %q; want %q", dst.Config.Host, want)
+	}
+	if want := "user"; dst.Config.DB.User != want {
+		t.Errorf("Getenv() DB.User = %q; want %q", dst.Config.DB.User, want)
+	}
+	if want := 2; len(dst.Config.Peers) != want {
+		t.Errorf("Getenv() len(Peers) = %d; want %d", len(dst.Config.Peers), want)
+	}
+
+	if !errors.Is(err, ErrRequired) {
+		t.Errorf("errors.Is(%v, ErrRequired) = false; want true", err)
+	}
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Str != "port" {
+		t.Errorf("errors.As(%v, *SyntaxError) = %v; want syntax error for %q", err, serr, "port")
+	}
+	if want := len(errs); strings.Count(err.Error(), "\n") != want-1 {
+		t.Errorf("Getenv() err = %q; want %d lines", err, want)
+	}
+}
+
+type fileData struct {
+	User     string
+	Password string   `envi:",file"`
+	Hosts    []string `envi:",file"`
+}
+
+func TestGetenvFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	hosts := filepath.Join(dir, "hosts")
+	if err := os.WriteFile(secret, []byte("hunter2\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hosts, []byte("a b\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("FileFlag", func(t *testing.T) {
+		r := Reader{Source: Values{
+			"test_User_FILE":     {secret},
+			"test_Password_FILE": {secret},
+			"test_Hosts_FILE":    {hosts},
+		}, Sep: "_"}
+		var got fileData
+		if err := r.Getenv(&got, "test"); err != nil {
+			t.Fatalf("Getenv() error = %v", err)
+		}
+		want := fileData{Password: "hunter2", Hosts: []string{"a", "b"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Getenv() = %#v; want %#v", got, want)
+		}
+	})
+
+	t.Run("FileSuffix", func(t *testing.T) {
+		r := Reader{Source: Values{"test_User__PATH": {secret}}, Sep: "_", FileSuffix: "__PATH"}
+		var got fileData
+		if err := r.Getenv(&got, "test"); err != nil {
+			t.Fatalf("Getenv() error = %v", err)
+		}
+		if want := (fileData{User: "hunter2"}); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Getenv() = %#v; want %#v", got, want)
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		r := Reader{Source: Values{
+			"test_Password":      {"x"},
+			"test_Password_FILE": {secret},
+		}, Sep: "_"}
+		var got fileData
+		err := r.Getenv(&got, "test")
+		var ke *KeyError
+		if !errors.Is(err, ErrFileConflict) || !errors.As(err, &ke) || ke.Key != "test_Password" {
+			t.Fatalf("Getenv() error = %v; want ErrFileConflict for test_Password", err)
+		}
+	})
+
+	t.Run("Unreadable", func(t *testing.T) {
+		r := Reader{Source: Values{"test_Password_FILE": {filepath.Join(dir, "missing")}}, Sep: "_"}
+		var got fileData
+		err := r.Getenv(&got, "test")
+		var ke *KeyError
+		if !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &ke) || ke.Key != "test_Password_FILE" {
+			t.Fatalf("Getenv() error = %v; want fs.ErrNotExist for test_Password_FILE", err)
+		}
+	})
+}
+
+func TestSplitTag(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{""}},
+		{"name", []string{"name"}},
+		{"name,quiet,sep=:", []string{"name", "quiet", "sep=:"}},
+		{`,default=a\,b`, []string{"", "default=a,b"}},
+		{`,default=a\\,b`, []string{"", `default=a\`, "b"}},
+		{`,default=C:\path`, []string{"", `default=C:\path`}},
+		{`,default=\`, []string{"", `default=\`}},
+	}
+
+	for _, c := range cases {
+		if got := splitTag(c.in); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitTag(%q) = %q; want %q", c.in, got, c.want)
+		}
+	}
+}
+
 func TestGetenvURL(t *testing.T) {
 	var (
 		newurl    = func() interface{} { return new(url.URL) }
@@ -880,6 +1337,84 @@ func TestGetenvScalarSlices(t *testing.T) {
 	})
 }
 
+func TestGetenvArrays(t *testing.T) {
+	testGetenv(t, []getenvTest{
+		{dst: func() interface{} { return new([3]float64) },
+			want: [3]float64{0.5, 1, 0.25},
+			in:   envone("0.5, 1, 0.25"),
+		},
+		{dst: func() interface{} { return new([4]byte) },
+			want: [4]byte{10, 0, 0, 1},
+			in:   envmany{"10", "0", "0", "1"},
+		},
+		// fewer elements than the array's length
+		{dst: func() interface{} { return &[3]int{7, 8, 9} },
+			want: [3]int{1, 2, 0},
+			in:   envone("1,2"),
+		},
+		// more elements than the array's length
+		{dst: func() interface{} { return new([2]string) },
+			bad:  true,
+			want: [2]string{},
+			in:   envone("a,b,c"),
+		},
+		{dst: func() interface{} { return new([2]int) },
+			want:  [2]int{},
+			empty: true,
+			in:    envone(""),
+		},
+	})
+}
+
+func TestGetenvArraysSeq(t *testing.T) {
+	type pair struct {
+		Host string
+		Port int
+	}
+	type config struct {
+		Pairs  [2]pair
+		Strict [2]int `envi:",strict"`
+	}
+
+	r := Reader{Source: Values{
+		"test_Pairs_1_Host": {"a"},
+		"test_Pairs_1_Port": {"1"},
+		"test_Pairs_2_Host": {"b"},
+		"test_Strict":       {"1", "2"},
+	}, Sep: "_"}
+	var got config
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+	want := config{Pairs: [2]pair{{"a", 1}, {"b", 0}}, Strict: [2]int{1, 2}}
+	if got != want {
+		t.Fatalf("Getenv() = %+v; want %+v", got, want)
+	}
+
+	for name, in := range map[string]Values{
+		"Short": {"test_Strict": {"1"}},
+		"Long":  {"test_Strict": {"1", "2", "3"}},
+		"Seq":   {"test_Pairs_1_Host": {"a"}, "test_Pairs_2_Host": {"b"}, "test_Pairs_3_Host": {"c"}},
+	} {
+		r.Source = in
+		if err := r.Getenv(new(config), "test"); !errors.Is(err, ErrArrayLength) {
+			t.Errorf("%s: Getenv() error = %v; want ErrArrayLength", name, err)
+		}
+	}
+
+	// Invalid elements report their own errors, even though fewer elements were loaded.
+	for name, in := range map[string]Values{
+		"Split": {"test_Strict": {"1", "x"}},
+		"Seq":   {"test_Strict_1": {"1"}, "test_Strict_2": {"x"}},
+	} {
+		r.Source = in
+		var serr *SyntaxError
+		if err := r.Getenv(new(config), "test"); errors.Is(err, ErrArrayLength) || !errors.As(err, &serr) {
+			t.Errorf("%s: Getenv() error = %v; want a *SyntaxError", name, err)
+		}
+	}
+}
+
 func TestGetenvDuration(t *testing.T) {
 	newdur := func() interface{} { return new(time.Duration) }
 	testGetenv(t, []getenvTest{
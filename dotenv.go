@@ -0,0 +1,276 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DotenvError is a syntax error encountered while parsing dotenv text.
+type DotenvError struct {
+	Name string // The name of the dotenv text, typically a file name
+	Line int    // The 1-based line number the error occurred on
+	Err  error
+}
+
+func (e *DotenvError) Error() string {
+	return e.Name + ":" + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *DotenvError) Unwrap() error {
+	return e.Err
+}
+
+// Errors returned by ParseDotenv, held by a *DotenvError.
+var (
+	ErrDotenvKey      = errors.New("invalid key")
+	ErrDotenvAssign   = errors.New("expected = after key")
+	ErrDotenvQuote    = errors.New("unterminated quoted value")
+	ErrDotenvTrailing = errors.New("unexpected text after quoted value")
+)
+
+// ReadDotenv reads and parses the dotenv file at path. See ParseDotenv for more information.
+func ReadDotenv(path string) (Values, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseDotenv(f, path)
+}
+
+// ParseDotenv parses dotenv text read from r and returns the variables it defines as Values. The
+// name is used to identify the text in errors, and is typically a file name. If a key is defined
+// more than once, each of its values is added to the key in order, so the result can be used as a
+// Multienv.
+//
+// Each line of dotenv text is either blank, a comment beginning with #, or a KEY=VALUE assignment
+// optionally preceded by "export". Keys may contain letters, digits, underscores, and periods, and
+// may not begin with a digit. Values may be:
+//
+//   - Unquoted, in which case the value runs to the end of the line, excluding surrounding
+//     whitespace and any comment (a # preceded by whitespace).
+//   - Single-quoted, in which case the value is taken literally up to the closing quote.
+//   - Double-quoted, in which case the escape sequences \n, \r, \t, \", \\, \$, and \` are
+//     replaced by the characters they represent. Other backslashes are kept as-is.
+//
+// Quoted values may span multiple lines and may only be followed by whitespace and a comment.
+// Syntax errors are returned as a *DotenvError.
+func ParseDotenv(r io.Reader, name string) (Values, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := dotenvParser{src: string(src), line: 1}
+	vals := make(Values)
+	for {
+		key, val, ok, err := p.next()
+		if err != nil {
+			return nil, &DotenvError{Name: name, Line: p.errLine, Err: err}
+		} else if !ok {
+			return vals, nil
+		}
+		vals.Add(key, val)
+	}
+}
+
+type dotenvParser struct {
+	src     string
+	pos     int
+	line    int
+	errLine int
+}
+
+func (p *dotenvParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+// skipSpace skips spaces and tabs, but not newlines.
+func (p *dotenvParser) skipSpace() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.pos++
+	}
+}
+
+// skipLine skips the remainder of the current line, including its newline.
+func (p *dotenvParser) skipLine() {
+	if i := strings.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+		p.pos += i + 1
+		p.line++
+	} else {
+		p.pos = len(p.src)
+	}
+}
+
+// endLine consumes the rest of a line following a value, which may only be whitespace and a
+// comment.
+func (p *dotenvParser) endLine() error {
+	p.skipSpace()
+	switch c := p.peek(); c {
+	case 0, '\n', '#':
+		p.skipLine()
+		return nil
+	case '\r':
+		if p.pos+1 == len(p.src) || p.src[p.pos+1] == '\n' {
+			p.skipLine()
+			return nil
+		}
+	}
+	return ErrDotenvTrailing
+}
+
+func (p *dotenvParser) fail(err error) (key, val string, ok bool, _ error) {
+	if p.errLine == 0 {
+		p.errLine = p.line
+	}
+	return "", "", false, err
+}
+
+// next parses the next assignment in the text. If there are no more assignments, ok is false.
+func (p *dotenvParser) next() (key, val string, ok bool, err error) {
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return "", "", false, nil
+		}
+		if c := p.peek(); c == '\n' || c == '\r' || c == '#' {
+			p.skipLine()
+			continue
+		}
+		break
+	}
+
+	if key = p.key(); key == "export" {
+		if c := p.peek(); c == ' ' || c == '\t' {
+			p.skipSpace()
+			key = p.key()
+		}
+	}
+	if key == "" {
+		return p.fail(ErrDotenvKey)
+	}
+
+	p.skipSpace()
+	if p.peek() != '=' {
+		return p.fail(ErrDotenvAssign)
+	}
+	p.pos++
+	eq := p.pos
+	p.skipSpace()
+
+	switch p.peek() {
+	case '\'':
+		val, err = p.singleQuoted()
+	case '"':
+		val, err = p.doubleQuoted()
+	default:
+		return key, p.unquoted(p.pos > eq), true, nil
+	}
+
+	if err == nil {
+		err = p.endLine()
+	}
+	if err != nil {
+		return p.fail(err)
+	}
+	return key, val, true, nil
+}
+
+func isKeyByte(c byte, first bool) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') ||
+		(!first && (c == '.' || ('0' <= c && c <= '9')))
+}
+
+// key returns the key at the current position, or the empty string if there isn't one.
+func (p *dotenvParser) key() string {
+	start := p.pos
+	for !p.eof() && isKeyByte(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// unquoted returns the unquoted value at the current position. If spaced is true, the value was
+// preceded by whitespace, so a # at its start begins a comment.
+func (p *dotenvParser) unquoted(spaced bool) string {
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.src) - p.pos
+	}
+	val := p.src[p.pos : p.pos+end]
+	for i := 0; i < len(val); i++ {
+		if val[i] == '#' && ((i == 0 && spaced) || (i > 0 && (val[i-1] == ' ' || val[i-1] == '\t'))) {
+			val = val[:i]
+			break
+		}
+	}
+	p.pos += end
+	p.skipLine()
+	return strings.TrimRight(val, " \t\r")
+}
+
+func (p *dotenvParser) singleQuoted() (string, error) {
+	p.errLine = p.line
+	p.pos++ // Opening quote
+	end := strings.IndexByte(p.src[p.pos:], '\'')
+	if end < 0 {
+		return "", ErrDotenvQuote
+	}
+	val := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	p.line += strings.Count(val, "\n")
+	p.errLine = 0
+	return val, nil
+}
+
+func (p *dotenvParser) doubleQuoted() (string, error) {
+	p.errLine = p.line
+	p.pos++ // Opening quote
+
+	var b strings.Builder
+	for !p.eof() {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			p.errLine = 0
+			return b.String(), nil
+		case '\n':
+			p.line++
+		case '\\':
+			if p.eof() {
+				break
+			}
+			switch e := p.src[p.pos]; e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case '"', '\\', '$', '`':
+				c = e
+			default:
+				b.WriteByte('\\')
+				continue
+			}
+			p.pos++
+		}
+		b.WriteByte(c)
+	}
+	return "", ErrDotenvQuote
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	const src = `# A comment
+PLAIN=value
+export EXPORTED=exported value
+SPACED = spaced  # trailing comment
+HASH=a#b
+EMPTY=
+COMMENT= # only a comment
+SINGLE='single $HOME \n "quoted"'
+DOUBLE="line\nbreak \"quoted\" \$HOME \\ \q"
+MULTI="first
+second"
+MULTI_SINGLE='first
+second' # comment
+REPEATED=1
+REPEATED=2
+CRLF=crlf` + "\r\n" + `dotted.key=dot
+export=not exported
+   INDENTED=indented
+LAST=last`
+
+	want := Values{
+		"PLAIN":        {"value"},
+		"EXPORTED":     {"exported value"},
+		"SPACED":       {"spaced"},
+		"HASH":         {"a#b"},
+		"EMPTY":        {""},
+		"COMMENT":      {""},
+		"SINGLE":       {`single $HOME \n "quoted"`},
+		"DOUBLE":       {"line\nbreak \"quoted\" $HOME \\ \\q"},
+		"MULTI":        {"first\nsecond"},
+		"MULTI_SINGLE": {"first\nsecond"},
+		"REPEATED":     {"1", "2"},
+		"CRLF":         {"crlf"},
+		"dotted.key":   {"dot"},
+		"export":       {"not exported"},
+		"INDENTED":     {"indented"},
+		"LAST":         {"last"},
+	}
+
+	got, err := ParseDotenv(strings.NewReader(src), "test.env")
+	if err != nil {
+		t.Fatalf("ParseDotenv() err = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDotenv() = %q;\nwant %q", got, want)
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	cases := []struct {
+		src  string
+		line int
+		err  error
+	}{
+		{"1KEY=value", 1, ErrDotenvKey},
+		{"A=1\n=value", 2, ErrDotenvKey},
+		{"A=1\n\nKEY value", 3, ErrDotenvAssign},
+		{"A=1\nexport KEY", 2, ErrDotenvAssign},
+		{"A=1\nKEY='value\n\n", 2, ErrDotenvQuote},
+		{"A=1\nKEY=\"value\n\\\"", 2, ErrDotenvQuote},
+		{"A=\"1\n2\" trailing", 2, ErrDotenvTrailing},
+	}
+
+	for _, c := range cases {
+		_, err := ParseDotenv(strings.NewReader(c.src), "test.env")
+
+		var derr *DotenvError
+		if !errors.As(err, &derr) {
+			t.Errorf("ParseDotenv(%q) err = %v; want *DotenvError", c.src, err)
+			continue
+		}
+		if derr.Name != "test.env" || derr.Line != c.line || derr.Err != c.err {
+			t.Errorf("ParseDotenv(%q) err = %v; want test.env:%d: %v", c.src, err, c.line, c.err)
+		}
+	}
+}
+
+func TestReadDotenv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("HOSTS=a\nHOSTS=b\nPORT=80\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+
+	vals, err := ReadDotenv(path)
+	if err != nil {
+		t.Fatalf("ReadDotenv() err = %v; want nil", err)
+	}
+
+	var cfg struct {
+		Hosts []string `envi:"HOSTS"`
+		Port  int      `envi:"PORT"`
+	}
+	r := Reader{Source: vals}
+	if err := r.Getenv(&cfg, ""); err != nil {
+		t.Fatalf("Getenv() err = %v; want nil", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(cfg.Hosts, want) || cfg.Port != 80 {
+		t.Errorf("Getenv() = %+v; want Hosts=%q Port=80", cfg, want)
+	}
+
+	if _, err := ReadDotenv(path + ".missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadDotenv(missing) err = %v; want not exist", err)
+	}
+}
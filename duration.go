@@ -0,0 +1,199 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	errDurationSyntax   = errors.New("invalid duration")
+	errDurationRange    = errors.New("duration out of range")
+	errDurationVariable = errors.New("years and months do not have a fixed duration")
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// loadDuration loads val into out. If extended durations are enabled by the Reader or the field's
+// extended flag, val may use the extended syntax described under Reader.ExtendedDurations. If the
+// field has a unit flag, a bare number in val is a number of that unit (e.g., 30 is 30s for
+// unit=s).
+func (r readState) loadDuration(out *time.Duration, val string) (err error) {
+	if val == "" {
+		return ErrNoValue
+	}
+
+	var d time.Duration
+	switch {
+	case r.flags.unit != "" && isBareNumber(val):
+		d, err = parseExtendedDuration(val + r.flags.unit)
+	case r.ExtendedDurations || r.flags.extended:
+		d, err = parseExtendedDuration(val)
+	default:
+		d, err = time.ParseDuration(val)
+	}
+	if err == nil {
+		*out = d
+	}
+	return err
+}
+
+// isBareNumber returns whether s is a decimal number without a unit.
+func isBareNumber(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	digits := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9':
+			digits = true
+		case c != '.':
+			return false
+		}
+	}
+	return digits
+}
+
+// parseExtendedDuration parses s as either a time.ParseDuration duration that may also have the
+// units d (days) and w (weeks), or as an ISO 8601 duration (e.g., PT15M). Either may be signed.
+func parseExtendedDuration(s string) (d time.Duration, err error) {
+	rest, neg := s, false
+	if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+		rest, neg = rest[1:], rest[0] == '-'
+	}
+
+	if strings.HasPrefix(rest, "P") {
+		d, err = parseISODuration(rest[1:])
+	} else {
+		d, err = parseUnitDuration(rest)
+	}
+	if err != nil {
+		return 0, mksyntaxerr(s, err)
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// parseUnitDuration parses an unsigned time.ParseDuration duration that may also have the units
+// d and w.
+func parseUnitDuration(s string) (d time.Duration, err error) {
+	if s == "0" {
+		return 0, nil
+	} else if s == "" {
+		return 0, errDurationSyntax
+	}
+
+	for s != "" {
+		n := strings.IndexFunc(s, func(c rune) bool { return (c < '0' || c > '9') && c != '.' })
+		if n <= 0 {
+			return 0, errDurationSyntax
+		}
+		u := strings.IndexAny(s[n:], "0123456789.")
+		if u == -1 {
+			u = len(s) - n
+		}
+		num, unit := s[:n], s[n:n+u]
+		s = s[n+u:]
+
+		var part time.Duration
+		switch unit {
+		case "d":
+			part, err = scaleDuration(num, day)
+		case "w":
+			part, err = scaleDuration(num, week)
+		default:
+			if part, err = time.ParseDuration(num + unit); err != nil {
+				err = errDurationSyntax
+			}
+		}
+		if err != nil {
+			return 0, err
+		} else if d, err = addDuration(d, part); err != nil {
+			return 0, err
+		}
+	}
+	return d, nil
+}
+
+// parseISODuration parses the remainder of an unsigned ISO 8601 duration following its P (e.g.,
+// 1DT12H for P1DT12H). Years and months are rejected, since their lengths vary.
+func parseISODuration(s string) (d time.Duration, err error) {
+	date, clock := s, ""
+	if i := strings.IndexByte(s, 'T'); i != -1 {
+		date, clock = s[:i], s[i+1:]
+		if clock == "" {
+			return 0, errDurationSyntax
+		}
+	}
+	if date == "" && clock == "" {
+		return 0, errDurationSyntax
+	}
+
+	parts := []struct {
+		s     string
+		units string
+		scale []time.Duration
+	}{
+		{date, "YMWD", []time.Duration{0, 0, week, day}},
+		{clock, "HMS", []time.Duration{time.Hour, time.Minute, time.Second}},
+	}
+	for _, p := range parts {
+		s, next := p.s, 0
+		for s != "" {
+			n := strings.IndexFunc(s, func(c rune) bool { return (c < '0' || c > '9') && c != '.' && c != ',' })
+			if n <= 0 {
+				return 0, errDurationSyntax
+			}
+			// Designators must appear at most once, in order.
+			i := strings.IndexByte(p.units[next:], s[n])
+			if i == -1 {
+				return 0, errDurationSyntax
+			}
+			i += next
+			next = i + 1
+			if p.scale[i] == 0 {
+				return 0, errDurationVariable
+			}
+
+			part, err := scaleDuration(strings.Replace(s[:n], ",", ".", 1), p.scale[i])
+			if err != nil {
+				return 0, err
+			} else if d, err = addDuration(d, part); err != nil {
+				return 0, err
+			}
+			s = s[n+1:]
+		}
+	}
+	return d, nil
+}
+
+// scaleDuration returns the decimal number num multiplied by scale, truncated to a whole number of
+// nanoseconds.
+func scaleDuration(num string, scale time.Duration) (time.Duration, error) {
+	n, ok := new(big.Rat).SetString(num)
+	if !ok || strings.ContainsAny(num, "/eE") || n.Sign() < 0 {
+		return 0, errDurationSyntax
+	}
+	n.Mul(n, new(big.Rat).SetInt64(int64(scale)))
+	i := new(big.Int).Quo(n.Num(), n.Denom())
+	if !i.IsInt64() {
+		return 0, errDurationRange
+	}
+	return time.Duration(i.Int64()), nil
+}
+
+// addDuration returns a + b for non-negative durations, or an error if the sum overflows.
+func addDuration(a, b time.Duration) (time.Duration, error) {
+	if sum := a + b; sum >= a {
+		return sum, nil
+	}
+	return 0, errDurationRange
+}
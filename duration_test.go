@@ -0,0 +1,98 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseExtendedDuration(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Duration
+		err  error
+	}{
+		{in: "0", want: 0},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "30d", want: 30 * day},
+		{in: "1w2d12h", want: week + 2*day + 12*time.Hour},
+		{in: "1.5d", want: 36 * time.Hour},
+		{in: "-2d", want: -2 * day},
+		{in: "PT15M", want: 15 * time.Minute},
+		{in: "P1DT12H", want: 36 * time.Hour},
+		{in: "P2W", want: 2 * week},
+		{in: "PT0.5S", want: 500 * time.Millisecond},
+		{in: "PT1,5S", want: 1500 * time.Millisecond},
+		{in: "-PT1M", want: -time.Minute},
+		{in: "P1Y", err: errDurationVariable},
+		{in: "P1M", err: errDurationVariable},
+		{in: "PT1M1H", err: errDurationSyntax},
+		{in: "P1DT", err: errDurationSyntax},
+		{in: "P", err: errDurationSyntax},
+		{in: "PT1X", err: errDurationSyntax},
+		{in: "1x", err: errDurationSyntax},
+		{in: "30", err: errDurationSyntax},
+		{in: "d", err: errDurationSyntax},
+		{in: "200000w", err: errDurationRange},
+		{in: "PT9223372036854775807S", err: errDurationRange},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.in, func(t *testing.T) {
+			got, err := parseExtendedDuration(c.in)
+			if c.err != nil {
+				var se *SyntaxError
+				if !errors.Is(err, c.err) || !errors.As(err, &se) || se.Str != c.in {
+					t.Fatalf("parseExtendedDuration() error = %v; want %v", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseExtendedDuration() error = %v", err)
+			} else if got != c.want {
+				t.Fatalf("parseExtendedDuration() = %v; want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestGetenvExtendedDurations(t *testing.T) {
+	type config struct {
+		Retention time.Duration   `envi:",extended"`
+		Timeout   time.Duration   `envi:",unit=s"`
+		Backoff   []time.Duration `envi:",unit=ms"`
+		Plain     time.Duration
+	}
+
+	env := Values{
+		"test_Retention": {"30d"},
+		"test_Timeout":   {"30"},
+		"test_Backoff":   {"100", "1.5s"},
+		"test_Plain":     {"PT15M"},
+	}
+
+	// Without ExtendedDurations, only the field with the extended flag accepts the syntax.
+	r := Reader{Source: env, Sep: "_"}
+	if err := r.Getenv(new(config), "test"); err == nil {
+		t.Fatal("Getenv() error = <nil>; want error for Plain")
+	}
+
+	r.ExtendedDurations = true
+	var got config
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+	want := config{
+		Retention: 30 * day,
+		Timeout:   30 * time.Second,
+		Backoff:   []time.Duration{100 * time.Millisecond, 1500 * time.Millisecond},
+		Plain:     15 * time.Minute,
+	}
+	if got.Retention != want.Retention || got.Timeout != want.Timeout || got.Plain != want.Plain ||
+		len(got.Backoff) != 2 || got.Backoff[0] != want.Backoff[0] || got.Backoff[1] != want.Backoff[1] {
+		t.Fatalf("Getenv() = %+v; want %+v", got, want)
+	}
+}
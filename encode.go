@@ -0,0 +1,234 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"encoding"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshal encodes src as environment variables identified by key, returning them as Values. It is
+// the inverse of Getenv: struct fields, slice indices, and map keys are joined to key using the
+// same field tags, Sep, and Names as Getenv, so that calling Getenv with the result as the Source
+// reproduces src.
+//
+// Values are encoded using their MarshalEnv or MarshalText methods if they have them. Slices and
+// arrays are encoded as key_1 through key_N, so that every key holds a single value and the result
+// can be written to a process's environment (see Values.Environ). Nil pointers, nil interfaces, and
+// empty slices and maps are omitted. Values that cannot be encoded, such as channels and
+// functions, cause Marshal to return an *UnsupportedTypeError, and map keys containing Sep whose
+// values are structs, maps, slices, or arrays cause it to return ErrMapKeySep in a *KeyError.
+func (r *Reader) Marshal(src interface{}, key string) (Values, error) {
+	vals := make(Values)
+	if err := r.encode(vals, reflect.ValueOf(src), key, ""); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// encode encodes v, identified by key, into vals. Times in v, including the elements and keys of
+// slices and maps, are formatted using layout, if it isn't empty (see the layout flag).
+func (r *Reader) encode(vals Values, v reflect.Value, key, layout string) error {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+
+	if s, ok, err := marshalValue(v, key, layout); err != nil {
+		return newKeyError(key, err)
+	} else if ok {
+		vals.Set(key, s)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return r.encode(vals, v.Elem(), key, layout)
+	case reflect.Slice, reflect.Array:
+		return r.encodeSlice(vals, v, key, layout)
+	case reflect.Map:
+		return r.encodeMap(vals, v, key, layout)
+	case reflect.Struct:
+		return r.encodeStruct(vals, v, key)
+	}
+	return &UnsupportedTypeError{v.Type()}
+}
+
+func (r *Reader) encodeSlice(vals Values, v reflect.Value, key, layout string) error {
+	// Elements are numbered from 1, skipping nil elements, so that they're loaded back in order.
+	n := 0
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		switch elem.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if elem.IsNil() {
+				continue
+			}
+		}
+		n++
+		if err := r.encode(vals, elem, key+r.Sep+strconv.Itoa(n), layout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reader) encodeMap(vals Values, v reflect.Value, key, layout string) error {
+	prefix := key
+	if prefix != "" {
+		prefix += r.Sep
+	}
+
+	keys := v.MapKeys()
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		name, ok, err := marshalValue(k, key, layout)
+		if err != nil {
+			return newKeyError(key, err)
+		} else if !ok {
+			return &UnsupportedTypeError{k.Type()}
+		}
+		names[i] = name
+	}
+
+	// Keys of values held beneath their own key can't contain Sep, since it ends them when loading.
+	compound := r.Sep != "" && r.hasSubkeys(v.Type().Elem())
+
+	// Encode in a consistent order so that errors are consistent.
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return names[order[i]] < names[order[j]] })
+
+	for _, i := range order {
+		if compound && strings.Contains(names[i], r.Sep) {
+			return newKeyError(prefix+names[i], ErrMapKeySep)
+		}
+		if err := r.encode(vals, v.MapIndex(keys[i]), prefix+names[i], layout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reader) encodeStruct(vals Values, v reflect.Value, key string) error {
+	typ := v.Type()
+	for i, n := 0, typ.NumField(); i < n; i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		var (
+			etag  = splitTag(f.Tag.Get("envi"))
+			flags = structFlags{sep: r.Sep}
+		)
+		flags.parse(etag[1:])
+		if etag[0] == "-" {
+			continue
+		}
+
+		fname := flags.fieldName(key, etag[0], r.mapName(f.Name))
+		if err := r.encode(vals, v.Field(i), fname, flags.layout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// implementer returns v as an iface, or a pointer to v if only its pointer implements iface. If
+// neither do, ok is false.
+func implementer(v reflect.Value, iface reflect.Type) (impl interface{}, ok bool) {
+	if v.Type().Implements(iface) {
+		return v.Interface(), true
+	} else if v.Kind() == reflect.Ptr || !reflect.PtrTo(v.Type()).Implements(iface) {
+		return nil, false
+	}
+
+	if !v.CanAddr() {
+		tmp := reflect.New(v.Type()).Elem()
+		tmp.Set(v)
+		v = tmp
+	}
+	return v.Addr().Interface(), true
+}
+
+// marshalValue is marshalScalar, except that times are formatted using layout, if it isn't empty.
+func marshalValue(v reflect.Value, key, layout string) (s string, ok bool, err error) {
+	if tv := reflect.Indirect(v); layout != "" && tv.IsValid() && tv.Type() == timeType {
+		return formatTime(tv.Interface().(time.Time), layout), true, nil
+	}
+	return marshalScalar(v, key)
+}
+
+// marshalScalar returns v, identified by key, as environment variable text. If v cannot be
+// represented by a single value (e.g., it's a struct or a slice of integers), ok is false.
+func marshalScalar(v reflect.Value, key string) (s string, ok bool, err error) {
+	for {
+		if m, ok := implementer(v, marshalerType); ok {
+			s, err = m.(Marshaler).MarshalEnv(key)
+			return s, true, err
+		} else if m, ok := implementer(v, textMarshalerType); ok {
+			var text []byte
+			text, err = m.(encoding.TextMarshaler).MarshalText()
+			return string(text), true, err
+		} else if v.Type() == reflect.TypeOf((*time.Location)(nil)) && !v.IsNil() {
+			// Locations are only loaded through pointers (see pointerTypes).
+			return v.Interface().(*time.Location).String(), true, nil
+		}
+
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+
+	switch v.Type() {
+	case reflect.TypeOf(time.Duration(0)):
+		return time.Duration(v.Int()).String(), true, nil
+	case reflect.TypeOf(url.URL{}):
+		u := v.Interface().(url.URL)
+		return u.String(), true, nil
+	case reflect.TypeOf([]byte(nil)):
+		return string(v.Bytes()), true, nil
+	case reflect.TypeOf(net.IPNet{}):
+		ipnet := v.Interface().(net.IPNet)
+		return ipnet.String(), true, nil
+	case reflect.TypeOf(net.HardwareAddr(nil)):
+		return v.Interface().(net.HardwareAddr).String(), true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true, nil
+	}
+	return "", false, nil
+}
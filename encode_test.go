@@ -0,0 +1,223 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// upperString is a Marshaler and Unmarshaler that stores its value in upper case.
+type upperString string
+
+func (s upperString) MarshalEnv(key string) (string, error) {
+	if s == "" {
+		return "", errors.New("empty upperString")
+	}
+	return strings.ToLower(string(s)), nil
+}
+
+func (s *upperString) UnmarshalEnv(key, value string) error {
+	*s = upperString(strings.ToUpper(value))
+	return nil
+}
+
+type encodeChild struct {
+	Name  string
+	Ports []uint16 `envi:"PORTS"`
+}
+
+type encodeData struct {
+	Int      int           `envi:"INT"`
+	Float    float64       `envi:"FLOAT"`
+	Bool     bool          `envi:"BOOL"`
+	Str      string        `envi:"STR"`
+	Bytes    []byte        `envi:"BYTES"`
+	Duration time.Duration `envi:"DURATION"`
+	Time     time.Time     `envi:"TIME"`
+	URL      *url.URL      `envi:"URL"`
+	Upper    upperString   `envi:"UPPER"`
+	Strs     []string      `envi:"STRS"`
+	RGB      [3]uint8      `envi:"RGB"`
+	Child    encodeChild   `envi:"CHILD,sep=:"`
+	Children []*encodeChild
+	Limits   map[string]int64 `envi:"LIMITS"`
+	Skipped  int              `envi:"-"`
+	Nil      *encodeChild     `envi:"NIL"`
+
+	unexported int
+}
+
+func TestMarshal(t *testing.T) {
+	src := encodeData{
+		Int:      -1234,
+		Float:    0.5,
+		Bool:     true,
+		Str:      "a string",
+		Bytes:    []byte("some bytes"),
+		Duration: 90 * time.Second,
+		Time:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		URL:      &url.URL{Scheme: "https", Host: "example.com", Path: "/"},
+		Upper:    "UPPER",
+		Strs:     []string{"a b", "c"},
+		RGB:      [3]uint8{255, 128, 0},
+		Child:    encodeChild{Name: "child", Ports: []uint16{80, 443}},
+		Children: []*encodeChild{{Name: "first"}, {Name: "second", Ports: []uint16{22}}},
+		Limits:   map[string]int64{"a": 1, "b_c": 2},
+		Skipped:  1,
+
+		unexported: 1,
+	}
+
+	want := Values{
+		"test_INT":                {"-1234"},
+		"test_FLOAT":              {"0.5"},
+		"test_BOOL":               {"true"},
+		"test_STR":                {"a string"},
+		"test_BYTES":              {"some bytes"},
+		"test_DURATION":           {"1m30s"},
+		"test_TIME":               {"2020-01-02T03:04:05Z"},
+		"test_URL":                {"https://example.com/"},
+		"test_UPPER":              {"upper"},
+		"test_STRS_1":             {"a b"},
+		"test_STRS_2":             {"c"},
+		"test_RGB_1":              {"255"},
+		"test_RGB_2":              {"128"},
+		"test_RGB_3":              {"0"},
+		"test:CHILD_Name":         {"child"},
+		"test:CHILD_PORTS_1":      {"80"},
+		"test:CHILD_PORTS_2":      {"443"},
+		"test_Children_1_Name":    {"first"},
+		"test_Children_2_Name":    {"second"},
+		"test_Children_2_PORTS_1": {"22"},
+		"test_LIMITS_a":           {"1"},
+		"test_LIMITS_b_c":         {"2"},
+	}
+
+	r := Reader{Sep: "_"}
+	got, err := r.Marshal(src, "test")
+	if err != nil {
+		t.Fatalf("Marshal() err = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Marshal() = %q;\nwant %q", got, want)
+	}
+
+	// Round trip
+	var dst encodeData
+	r.Source = got
+	if err := r.Getenv(&dst, "test"); err != nil {
+		t.Fatalf("Getenv() err = %v; want nil", err)
+	}
+	src.Skipped, src.unexported = 0, 0
+	if !reflect.DeepEqual(dst, src) {
+		t.Fatalf("Getenv() = %#v;\nwant %#v", dst, src)
+	}
+}
+
+func TestMarshalOSEnv(t *testing.T) {
+	type child struct {
+		Name  string
+		Ports []int
+	}
+	src := struct {
+		Strs     []string
+		RGB      [3]uint8
+		Children []*child
+	}{
+		Strs:     []string{"a b", "c"},
+		RGB:      [3]uint8{255, 128, 0},
+		Children: []*child{{Name: "first"}, nil, {Name: "second", Ports: []int{22}}},
+	}
+
+	r := Reader{Sep: "_"}
+	vals, err := r.Marshal(src, "ENVI_MARSHAL")
+	if err != nil {
+		t.Fatalf("Marshal() err = %v; want nil", err)
+	}
+	environ, err := vals.Environ()
+	if err != nil {
+		t.Fatalf("Environ() err = %v; want nil", err)
+	}
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+
+	dst := src
+	dst.Strs, dst.RGB, dst.Children = nil, [3]uint8{}, nil
+	r.Source = OSEnv
+	if err := r.Getenv(&dst, "ENVI_MARSHAL"); err != nil {
+		t.Fatalf("Getenv() err = %v; want nil", err)
+	}
+	src.Children = []*child{src.Children[0], src.Children[2]}
+	if !reflect.DeepEqual(dst, src) {
+		t.Fatalf("Getenv() = %#v;\nwant %#v", dst, src)
+	}
+}
+
+func TestMarshalNames(t *testing.T) {
+	src := struct {
+		MaxConns int
+		Tagged   int `envi:"tagged"`
+	}{1, 2}
+	want := Values{"APP_MAX_CONNS": {"1"}, "APP_tagged": {"2"}}
+
+	r := Reader{Sep: "_", Names: UpperSnake}
+	got, err := r.Marshal(&src, "APP")
+	if err != nil {
+		t.Fatalf("Marshal() err = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal() = %q; want %q", got, want)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	r := Reader{Sep: "_"}
+
+	var terr *UnsupportedTypeError
+	if _, err := r.Marshal(struct{ C chan int }{}, "test"); !errors.As(err, &terr) {
+		t.Errorf("Marshal(chan) err = %v; want *UnsupportedTypeError", err)
+	}
+
+	var kerr *KeyError
+	if _, err := r.Marshal(struct{ U upperString }{}, "test"); !errors.As(err, &kerr) || kerr.Key != "test_U" {
+		t.Errorf("Marshal(upperString) err = %v; want *KeyError for test_U", err)
+	}
+}
+
+func TestMarshalMapKeySep(t *testing.T) {
+	r := Reader{Sep: "_"}
+	cases := []interface{}{
+		map[string]encodeChild{"a_b": {Name: "x"}},
+		map[string][]int{"a_b": {1}},
+		map[string]map[string]int{"a_b": {"c": 1}},
+	}
+	for _, src := range cases {
+		var kerr *KeyError
+		if _, err := r.Marshal(src, "test"); !errors.Is(err, ErrMapKeySep) || !errors.As(err, &kerr) || kerr.Key != "test_a_b" {
+			t.Errorf("Marshal(%T) err = %v; want %v for test_a_b", src, err, ErrMapKeySep)
+		}
+	}
+
+	// Scalar values are loaded from the whole key, so it may contain Sep.
+	if _, err := r.Marshal(map[string]int{"a_b": 1}, "test"); err != nil {
+		t.Errorf("Marshal(map[string]int) err = %v; want nil", err)
+	}
+}
+
+func TestGlobalMarshal(t *testing.T) {
+	got, err := Marshal(1234, "test")
+	if err != nil {
+		t.Fatalf("Marshal() err = %v; want nil", err)
+	}
+	if want := (Values{"test": {"1234"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal() = %q; want %q", got, want)
+	}
+}
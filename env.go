@@ -3,7 +3,10 @@
 
 package envi
 
-import "os"
+import (
+	"os"
+	"strings"
+)
 
 // Env is an environment variable source. Given an identifying key, it must return a corresponding
 // value. If the resulting value is the empty string, it is considered unset for certain types.
@@ -19,8 +22,22 @@ type Multienv interface {
 	GetenvAll(key string) ([]string, error)
 }
 
+// KeyLister is an environment variable source capable of listing the keys it holds. If prefix is not
+// empty, only keys beginning with prefix are returned. Keys may be returned in any order.
+//
+// Sources that are KeyListers allow a Reader to load maps and avoid looking up slice indices that
+// don't exist.
+type KeyLister interface {
+	Keys(prefix string) ([]string, error)
+}
+
 type osenv int
 
+var (
+	_ Env       = OSEnv
+	_ KeyLister = OSEnv
+)
+
 func (osenv) Getenv(key string) (value string, err error) {
 	value, ok := os.LookupEnv(key)
 	if !ok {
@@ -29,12 +46,25 @@ func (osenv) Getenv(key string) (value string, err error) {
 	return value, nil
 }
 
+func (osenv) String() string { return "os" }
+
+func (osenv) Keys(prefix string) (keys []string, err error) {
+	for _, kv := range os.Environ() {
+		// Skip entries without a name (e.g., Windows' per-drive "=C:=C:\" variables).
+		if i := strings.IndexByte(kv, '='); i > 0 && strings.HasPrefix(kv[:i], prefix) {
+			keys = append(keys, kv[:i])
+		}
+	}
+	return keys, nil
+}
+
 // OSEnv is an Env implementation that can be used to simply return os.Getenv values. This will
 // allow empty values provided the environment variable is defined (i.e., LookupEnv returns a value
-// and OK=true).
+// and OK=true). OSEnv is also a KeyLister, listing the keys returned by os.Environ.
 const OSEnv osenv = 0
 
-// EnvFunc is a callback implementing Env.
+// EnvFunc is a callback implementing Env. An EnvFunc is not a KeyLister, so it can't be used to load
+// maps, and a Chain holding one can't list its keys, unless it is wrapped with WithKeys.
 type EnvFunc func(string) (string, error)
 
 // Getenv implements Env.
@@ -61,3 +91,28 @@ func (fn MultiEnvFunc) Getenv(key string) (string, error) {
 	}
 	return v[0], nil
 }
+
+// KeysFunc is a callback implementing KeyLister.
+type KeysFunc func(prefix string) ([]string, error)
+
+// Keys implements KeyLister.
+func (fn KeysFunc) Keys(prefix string) ([]string, error) { return fn(prefix) }
+
+// WithKeys returns an Env that gets values from env and lists its keys using keys, so that sources
+// such as EnvFunc can be KeyListers. If env is a Multienv, so is the returned Env.
+func WithKeys(env Env, keys KeysFunc) Env {
+	if m, ok := env.(Multienv); ok {
+		return keyedMultienv{m, keys}
+	}
+	return keyedEnv{env, keys}
+}
+
+type keyedEnv struct {
+	Env
+	KeysFunc
+}
+
+type keyedMultienv struct {
+	Multienv
+	KeysFunc
+}
@@ -5,6 +5,8 @@ package envi
 
 import (
 	"os"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -60,3 +62,54 @@ func TestEnvFunc(t *testing.T) {
 	r := Reader{Source: EnvFunc(OSEnv.Getenv)}
 	testOSEnv(t, r.Getenv)
 }
+
+func TestOSEnvKeys(t *testing.T) {
+	const prefix = "ENVI_TEST_KEYS_"
+	for _, k := range []string{prefix + "A", prefix + "B"} {
+		if err := os.Setenv(k, ""); err != nil {
+			t.Skipf("os.Setenv() = %v; skipping", err)
+		}
+		defer os.Unsetenv(k)
+	}
+
+	keys, err := OSEnv.Keys(prefix)
+	if err != nil {
+		t.Fatalf("Keys(%q) err = %v; want nil", prefix, err)
+	}
+	sort.Strings(keys)
+
+	if want := []string{prefix + "A", prefix + "B"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys(%q) = %q; want %q", prefix, keys, want)
+	}
+}
+
+func TestWithKeys(t *testing.T) {
+	vals := Values{"test_a": {"1"}, "test_b": {"2", "3"}}
+	keys := KeysFunc(vals.Keys)
+
+	env := WithKeys(EnvFunc(vals.Getenv), keys)
+	if _, ok := env.(Multienv); ok {
+		t.Errorf("WithKeys(EnvFunc) is a Multienv")
+	}
+	var got map[string]int
+	r := Reader{Source: Chain{{Name: "func", Env: env}}, Sep: "_"}
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() err = %v; want nil", err)
+	}
+	if want := map[string]int{"a": 1, "b": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Getenv() = %v; want %v", got, want)
+	}
+
+	multi := WithKeys(MultiEnvFunc(vals.GetenvAll), keys)
+	if _, ok := multi.(Multienv); !ok {
+		t.Fatalf("WithKeys(MultiEnvFunc) is not a Multienv")
+	}
+	var all map[string][]int
+	r.Source = multi
+	if err := r.Getenv(&all, "test"); err != nil {
+		t.Fatalf("Getenv() err = %v; want nil", err)
+	}
+	if want := map[string][]int{"a": {1}, "b": {2, 3}}; !reflect.DeepEqual(all, want) {
+		t.Errorf("Getenv() = %v; want %v", all, want)
+	}
+}
@@ -17,6 +17,12 @@ type Unmarshaler interface {
 	UnmarshalEnv(key, value string) error
 }
 
+// Marshaler defines an interface that allows a type to declare it supports marshaling to
+// environment variable text. It is the inverse of Unmarshaler.
+type Marshaler interface {
+	MarshalEnv(key string) (string, error)
+}
+
 // Getenv attempts to load the value held by the environment variable key into dst using the
 // DefaultReader. If an error occurs, that error is returned. See Reader.Getenv for more
 // information.
@@ -29,3 +35,9 @@ func Getenv(dst interface{}, key string) error {
 func Load(dst interface{}, val, key string) error {
 	return DefaultReader.Load(dst, val, key)
 }
+
+// Marshal encodes src as environment variables identified by key using the DefaultReader. See
+// Reader.Marshal for more information.
+func Marshal(src interface{}, key string) (Values, error) {
+	return DefaultReader.Marshal(src, key)
+}
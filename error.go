@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 // IsNoValue is a convenience function returning whether the given error is a no-value error (i.e.,
@@ -33,6 +34,16 @@ func (t *TypeError) Error() string {
 	return "couldn't convert string to " + t.Type.String()
 }
 
+// UnsupportedTypeError is returned when marshaling a value whose type cannot be represented as
+// environment variable text.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (t *UnsupportedTypeError) Error() string {
+	return "couldn't convert " + t.Type.String() + " to string"
+}
+
 // SyntaxError is any syntax error encountered as a result of parsing values.
 type SyntaxError struct {
 	Str string
@@ -47,6 +58,11 @@ func (e *SyntaxError) Error() string {
 	return "syntax error: cannot parse " + strconv.Quote(e.Str) + errstr(e.Err)
 }
 
+// Unwrap returns the underlying error, if any.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
 func errstr(e error) string {
 	if e == nil {
 		return ""
@@ -57,10 +73,13 @@ func errstr(e error) string {
 	return ": " + e.Error()
 }
 
-// KeyError is any error encountered when unmarshaling a specific key.
+// KeyError is any error encountered when unmarshaling a specific key. If the key belongs to a
+// struct field, and the error is held by Errors, Field is the Go path of that field (e.g.,
+// "DB.Peers[0].Host").
 type KeyError struct {
-	Key string
-	Err error
+	Key   string
+	Field string
+	Err   error
 }
 
 func newKeyError(key string, err error) error {
@@ -77,8 +96,72 @@ func (k *KeyError) Error() string {
 	return k.Key + ": " + k.Err.Error()
 }
 
+// Unwrap returns the underlying error.
+func (k *KeyError) Unwrap() error {
+	return k.Err
+}
+
+// Errors is a list of errors encountered while loading the fields of a struct. It is returned by
+// Readers with AllErrors set. Like errors returned by errors.Join, its message is the message of
+// each error on separate lines and it supports errors.Is and errors.As by unwrapping to each error.
+type Errors []*KeyError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors held by e.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// MissingError is returned when one or more required struct fields have no value. Keys holds the
+// environment variable of each missing field, in the order the fields were loaded.
+type MissingError struct {
+	Keys []string
+}
+
+func (e *MissingError) Error() string {
+	return "missing required values: " + strings.Join(e.Keys, ", ")
+}
+
+// Is returns whether target is ErrRequired.
+func (e *MissingError) Is(target error) bool {
+	return target == ErrRequired
+}
+
 // ErrInvalidBool is returned if a boolean is not valid.
 var ErrInvalidBool = errors.New("bool is not valid")
 
+// ErrKeysUnavailable is returned if a map is loaded from an environment variable source that is not
+// a KeyLister.
+var ErrKeysUnavailable = errors.New("environment source cannot list keys")
+
+// ErrRequired is the error of a required struct field without a value.
+var ErrRequired = errors.New("required value is missing")
+
+// ErrFileConflict is returned when both a key and its file variable have values (see
+// Reader.FileSuffix).
+var ErrFileConflict = errors.New("value conflicts with file variable")
+
+// ErrArrayLength is returned when an array receives more values than its length, or, for fields
+// with the strict flag, a different number of values than its length.
+var ErrArrayLength = errors.New("wrong number of array elements")
+
+// ErrMultipleValues is returned by Values.Environ if a key holds more than one value.
+var ErrMultipleValues = errors.New("key holds multiple values")
+
+// ErrMapKeySep is returned by Marshal if a map key contains Sep and the map's values are encoded
+// beneath their own keys (e.g., structs and slices), since the key couldn't be loaded back.
+var ErrMapKeySep = errors.New("map key contains separator")
+
 // ErrNoValue is returned if a key has no value.
 var ErrNoValue = errors.New("no value")
@@ -0,0 +1,177 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrExpandCycle is returned when expanding a value that refers to itself, directly or through
+// other variables (see Reader.Expand).
+var ErrExpandCycle = errors.New("variable reference cycle")
+
+// ErrExpandUnset is returned when expanding ${VAR:?message} and VAR is unset or empty (see
+// Reader.Expand).
+var ErrExpandUnset = errors.New("variable is unset or empty")
+
+// errBadSubst is the error of a SyntaxError for a malformed ${...} reference.
+var errBadSubst = errors.New("bad variable substitution")
+
+// expand expands variable references in s, the value of key, if the Reader has Expand set.
+func (r readState) expand(key, s string) (string, error) {
+	if !r.Expand || !strings.Contains(s, "$") {
+		return s, nil
+	}
+	e := expander{getenv: r.getenv, stack: []string{key}}
+	v, err := e.expand(s)
+	if err != nil {
+		return "", newKeyError(key, err)
+	}
+	return v, nil
+}
+
+// expander expands variable references in values, resolving them with getenv. The stack holds
+// the keys being expanded, to detect reference cycles.
+type expander struct {
+	getenv func(string) (string, error)
+	stack  []string
+}
+
+func (e *expander) expand(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i == -1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+		s = s[i+1:]
+
+		switch {
+		case strings.HasPrefix(s, "$"):
+			b.WriteByte('$')
+			s = s[1:]
+		case strings.HasPrefix(s, "{"):
+			end := closingBrace(s)
+			if end == -1 {
+				return "", mksyntaxerr("$"+s, errBadSubst)
+			}
+			v, err := e.expandBraced(s[1:end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			s = s[end+1:]
+		default:
+			n := varNameLen(s)
+			if n == 0 {
+				// A lone $ is kept as-is.
+				b.WriteByte('$')
+				continue
+			}
+			v, _, err := e.lookup(s[:n])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			s = s[n:]
+		}
+	}
+}
+
+// expandBraced expands the body of a ${...} reference: NAME, NAME:-default, or NAME:?message.
+func (e *expander) expandBraced(ref string) (string, error) {
+	name, op := ref, ""
+	if i := strings.IndexByte(ref, ':'); i != -1 {
+		name, op = ref[:i], ref[i:]
+	}
+	if name == "" || varNameLen(name) != len(name) {
+		return "", mksyntaxerr("${"+ref+"}", errBadSubst)
+	}
+
+	v, set, err := e.lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case op == "":
+		return v, nil
+	case strings.HasPrefix(op, ":-"):
+		if set && v != "" {
+			return v, nil
+		}
+		return e.expand(op[2:])
+	case strings.HasPrefix(op, ":?"):
+		if set && v != "" {
+			return v, nil
+		}
+		msg, err := e.expand(op[2:])
+		if err != nil {
+			return "", err
+		} else if msg == "" {
+			return "", fmt.Errorf("%s: %w", name, ErrExpandUnset)
+		}
+		return "", fmt.Errorf("%s: %w: %s", name, ErrExpandUnset, msg)
+	}
+	return "", mksyntaxerr("${"+ref+"}", errBadSubst)
+}
+
+// lookup returns the expanded value of the variable name. If name is unset, it returns the empty
+// string and set is false.
+func (e *expander) lookup(name string) (v string, set bool, err error) {
+	for i, key := range e.stack {
+		if key == name {
+			cycle := append(e.stack[i:len(e.stack):len(e.stack)], name)
+			return "", false, fmt.Errorf("%w: %s", ErrExpandCycle, strings.Join(cycle, " -> "))
+		}
+	}
+
+	v, err = e.getenv(name)
+	if IsNoValue(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	e.stack = append(e.stack, name)
+	v, err = e.expand(v)
+	e.stack = e.stack[:len(e.stack)-1]
+	return v, err == nil, err
+}
+
+// closingBrace returns the index of the brace closing the brace at s[0], accounting for nested
+// ${...} references. If there is none, it returns -1.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// varNameLen returns the length of the variable name at the start of s. Names are made of ASCII
+// letters, digits, and underscores, and do not begin with a digit.
+func varNameLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return i
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,104 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	env := Values{
+		"DB_USER":  {"admin"},
+		"DB_PASS":  {"p$$w"},
+		"DB_HOST":  {"${HOST:-localhost}:${PORT}"},
+		"PORT":     {"5432"},
+		"EMPTY":    {""},
+		"CYCLE_A":  {"$CYCLE_B"},
+		"CYCLE_B":  {"x${CYCLE_A}"},
+		"SELF":     {"$SELF"},
+		"LIST":     {"$DB_USER", "${PORT}"},
+		"COST":     {"$5 and $"},
+		"NESTED":   {"${MISSING:-${DB_USER}}"},
+		"REQUIRED": {"${EMPTY:?must be set}"},
+		"BARE_REQ": {"${MISSING:?}"},
+		"BAD":      {"${DB-USER}"},
+		"OPEN":     {"${DB_USER"},
+	}
+
+	cases := []struct {
+		key  string
+		want string
+		err  error
+	}{
+		{key: "DB_HOST", want: "localhost:5432"},
+		{key: "DB_PASS", want: "p$w"},
+		{key: "COST", want: "$5 and $"},
+		{key: "NESTED", want: "admin"},
+		{key: "CYCLE_A", err: ErrExpandCycle},
+		{key: "SELF", err: ErrExpandCycle},
+		{key: "REQUIRED", err: ErrExpandUnset},
+		{key: "BARE_REQ", err: ErrExpandUnset},
+		{key: "BAD", err: errBadSubst},
+		{key: "OPEN", err: errBadSubst},
+	}
+
+	r := Reader{Source: env, Expand: true}
+	for _, c := range cases {
+		c := c
+		t.Run(c.key, func(t *testing.T) {
+			var got string
+			err := r.Getenv(&got, c.key)
+			if c.err != nil {
+				var ke *KeyError
+				if !errors.Is(err, c.err) || !errors.As(err, &ke) || ke.Key != c.key {
+					t.Fatalf("Getenv() error = %v; want %v for key %s", err, c.err, c.key)
+				}
+				return
+			}
+			if err != nil && !IsNoValue(err) {
+				t.Fatalf("Getenv() error = %v", err)
+			}
+			if got != c.want {
+				t.Fatalf("Getenv() = %q; want %q", got, c.want)
+			}
+		})
+	}
+
+	t.Run("Multienv", func(t *testing.T) {
+		var got []string
+		if err := r.Getenv(&got, "LIST"); err != nil {
+			t.Fatalf("Getenv() error = %v", err)
+		}
+		if want := []string{"admin", "5432"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Getenv() = %q; want %q", got, want)
+		}
+		if want := []string{"$DB_USER", "${PORT}"}; !reflect.DeepEqual(env["LIST"], want) {
+			t.Fatalf("Getenv() modified source values: %q", env["LIST"])
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		var got struct {
+			URL string `envi:",default=postgres://${DB_USER}@${DB_HOST}/app"`
+		}
+		if err := r.Getenv(&got, "APP"); !IsNoValue(err) {
+			t.Fatalf("Getenv() error = %v; want no value", err)
+		}
+		if want := "postgres://admin@localhost:5432/app"; got.URL != want {
+			t.Fatalf("Getenv() URL = %q; want %q", got.URL, want)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		var got string
+		r := Reader{Source: env}
+		if err := r.Getenv(&got, "DB_HOST"); err != nil {
+			t.Fatalf("Getenv() error = %v", err)
+		} else if want := env["DB_HOST"][0]; got != want {
+			t.Fatalf("Getenv() = %q; want %q", got, want)
+		}
+	})
+}
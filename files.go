@@ -0,0 +1,148 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const appendPrefix = "append:"
+
+// File open modes for the mode flag.
+const (
+	fileModeRead      = "r"
+	fileModeWrite     = "w"
+	fileModeAppend    = "a"
+	fileModeReadWrite = "rw"
+)
+
+var errNotWritable = errors.New("file is not opened for writing")
+
+// fileFlags returns the flags to open a file with for the mode flag, mode, which must not be empty.
+func fileFlags(mode string) (int, error) {
+	switch mode {
+	case fileModeRead:
+		return os.O_RDONLY, nil
+	case fileModeWrite:
+		return os.O_WRONLY | os.O_CREATE | os.O_TRUNC, nil
+	case fileModeAppend:
+		return os.O_WRONLY | os.O_CREATE | os.O_APPEND, nil
+	case fileModeReadWrite:
+		return os.O_RDWR | os.O_CREATE, nil
+	}
+	return 0, fmt.Errorf("unknown file mode %q", mode)
+}
+
+// filePerm returns the permissions to create files with for the perm flag, perm, which is an
+// octal number. If perm is empty, files are created with 0666 (before the umask).
+func filePerm(perm string) (os.FileMode, error) {
+	if perm == "" {
+		return 0o666, nil
+	}
+	p, err := strconv.ParseUint(perm, 8, 32)
+	if err != nil || p&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("invalid file permissions %q", perm)
+	}
+	return os.FileMode(p), nil
+}
+
+// openFile opens the file described by val, which is one of:
+//
+//   stdin, stdout, or stderr for the standard streams (- is also stdout)
+//   append:path to open path for appending, regardless of the field's mode
+//   path to open path using the field's mode and perm flags
+//
+// If the field has no mode flag, files are opened with defMode. Files other than the standard streams are tracked by the readState, so that they're closed if
+// loading fails.
+func (r readState) openFile(val, defMode string) (*os.File, error) {
+	switch val {
+	case "":
+		return nil, ErrNoValue
+	case "stdin":
+		return os.Stdin, nil
+	case "stdout", "-":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+
+	mode := r.flags.mode
+	if mode == "" {
+		mode = defMode
+	}
+	if strings.HasPrefix(val, appendPrefix) {
+		val, mode = val[len(appendPrefix):], fileModeAppend
+	}
+	flag, err := fileFlags(mode)
+	if err != nil {
+		return nil, err
+	}
+	perm, err := filePerm(r.flags.perm)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(val, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	r.track(f)
+	return f, nil
+}
+
+// loadFile opens the file described by val (see openFile), read-only unless the field has a mode
+// flag, so that loading a file never truncates it by default.
+func (r readState) loadFile(out **os.File, val string) error {
+	f, err := r.openFile(val, fileModeRead)
+	if err != nil {
+		return err
+	}
+	*out = f
+	return nil
+}
+
+func (r readState) loadWriter(out *io.Writer, val string) error {
+	if val == "stdin" || r.flags.mode == fileModeRead {
+		return mksyntaxerr(val, errNotWritable)
+	}
+	f, err := r.openFile(val, fileModeWrite)
+	if err != nil {
+		return err
+	}
+	*out = f
+	return nil
+}
+
+// track records that c was opened while loading, so that it's closed if loading fails.
+func (r readState) track(c io.Closer) {
+	if r.closers != nil {
+		*r.closers = append(*r.closers, c)
+	}
+}
+
+// closeAll closes everything tracked by the readState, most recently opened first.
+func (r readState) closeAll() {
+	if r.closers == nil {
+		return
+	}
+	for i := len(*r.closers) - 1; i >= 0; i-- {
+		(*r.closers)[i].Close()
+	}
+	*r.closers = nil
+}
+
+var writerType = reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+// isResourceType returns whether loading a value of type t may open a resource, such as a file or
+// listener: t is an io.Writer or io.Closer, or a type whose pointer is an io.Closer.
+func isResourceType(t reflect.Type) bool {
+	return t == writerType || t.Implements(closerType) ||
+		(t.Kind() != reflect.Interface && reflect.PtrTo(t).Implements(closerType))
+}
@@ -0,0 +1,152 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGetenvOutputFiles(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.log")
+	if err := os.WriteFile(existing, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		Errors  io.Writer
+		Out     *os.File
+		Audit   io.Writer `envi:",perm=0600"`
+		Append  io.Writer
+		Trunc   *os.File  `envi:",mode=w"`
+		Mode    io.Writer `envi:",mode=a"`
+		Streams []*os.File
+	}
+
+	r := Reader{Source: Values{
+		"test_Errors":  {"stderr"},
+		"test_Out":     {"-"},
+		"test_Audit":   {filepath.Join(dir, "audit.log")},
+		"test_Append":  {"append:" + existing},
+		"test_Trunc":   {filepath.Join(dir, "trunc.log")},
+		"test_Mode":    {existing},
+		"test_Streams": {"stdout", "stderr"},
+	}, Sep: "_"}
+
+	var got config
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+	if got.Errors != os.Stderr || got.Out != os.Stdout {
+		t.Errorf("Getenv() Errors, Out = %v, %v; want stderr, stdout", got.Errors, got.Out)
+	}
+	if len(got.Streams) != 2 || got.Streams[0] != os.Stdout || got.Streams[1] != os.Stderr {
+		t.Errorf("Getenv() Streams = %v; want [stdout stderr]", got.Streams)
+	}
+
+	for _, w := range []io.Writer{got.Audit, got.Append, got.Trunc, got.Mode} {
+		if _, err := io.WriteString(w, "new\n"); err != nil {
+			t.Fatalf("WriteString() error = %v", err)
+		}
+		w.(io.Closer).Close()
+	}
+	if b, _ := os.ReadFile(existing); string(b) != "old\nnew\nnew\n" {
+		t.Errorf("appended file = %q; want %q", b, "old\nnew\nnew\n")
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "audit.log")); err != nil {
+		t.Error(err)
+	} else if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm != 0o600 {
+		t.Errorf("audit.log permissions = %v; want 0600", perm)
+	}
+}
+
+func TestGetenvFileReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("keep\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var f *os.File
+	r := Reader{Source: Values{"F": {path}}}
+	if err := r.Getenv(&f, "F"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+	defer f.Close()
+
+	if b, err := io.ReadAll(f); err != nil || string(b) != "keep\n" {
+		t.Errorf("ReadAll() = %q, %v; want %q", b, err, "keep\n")
+	}
+	if _, err := f.WriteString("x"); err == nil {
+		t.Errorf("WriteString() error = <nil>; want an error writing a read-only file")
+	}
+	r.Source = Values{"F": {filepath.Join(filepath.Dir(path), "missing")}}
+	if err := r.Getenv(new(*os.File), "F"); err == nil {
+		t.Errorf("Getenv(missing) error = <nil>; want an error, since files aren't created by default")
+	}
+}
+
+func TestGetenvOutputFilesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name string
+		dst  interface{}
+	}{
+		{"ReadOnlyWriter", new(struct {
+			F io.Writer `envi:",mode=r"`
+		})},
+		{"Mode", new(struct {
+			F *os.File `envi:",mode=x"`
+		})},
+		{"Perm", new(struct {
+			F *os.File `envi:",perm=999"`
+		})},
+	}
+	for _, c := range cases {
+		r := Reader{Source: Values{"F": {filepath.Join(dir, c.name)}}}
+		if err := r.Getenv(c.dst, ""); err == nil || IsNoValue(err) {
+			t.Errorf("%s: Getenv() error = %v; want error", c.name, err)
+		}
+	}
+
+	r := Reader{Source: Values{"W": {"stdin"}}}
+	var w io.Writer
+	var se *SyntaxError
+	if err := r.Getenv(&w, "W"); !errors.As(err, &se) {
+		t.Errorf("Getenv(stdin) error = %v; want *SyntaxError", err)
+	}
+}
+
+func TestGetenvClosesOnFailure(t *testing.T) {
+	type config struct {
+		Log    *os.File `envi:",mode=w"`
+		Listen net.Listener
+		Port   int
+	}
+
+	r := Reader{Source: Values{
+		"test_Log":    {filepath.Join(t.TempDir(), "app.log")},
+		"test_Listen": {"tcp://127.0.0.1:0"},
+		"test_Port":   {"not a port"},
+	}, Sep: "_"}
+
+	var got config
+	if err := r.Getenv(&got, "test"); err == nil {
+		t.Fatal("Getenv() error = <nil>; want error")
+	}
+	if got.Log == nil || got.Listen == nil {
+		t.Fatalf("Getenv() = %+v; want Log and Listen assigned before failure", got)
+	}
+	if _, err := got.Log.WriteString("x"); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Log.WriteString() error = %v; want os.ErrClosed", err)
+	}
+	if _, err := got.Listen.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Listen.Accept() error = %v; want net.ErrClosed", err)
+	}
+}
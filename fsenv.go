@@ -0,0 +1,165 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FSEnv is an Env backed by the files of a directory in an fs.FS, such as a Kubernetes ConfigMap
+// or secret volume, systemd's $CREDENTIALS_DIRECTORY, an embed.FS, or an fstest.MapFS. The name of
+// each file is a key, and its contents, minus a trailing newline, are the key's value.
+//
+// Only regular files (or symlinks to them) directly under Dir are keys. Names beginning with a dot
+// are skipped, which excludes the ..data symlink and timestamped directories that Kubernetes uses
+// to update volumes atomically -- their files are read through the symlinks named after each key.
+//
+// FSEnv is also a Multienv, returning each line of a file as a separate value, and a KeyLister.
+type FSEnv struct {
+	// FS is the file system holding Dir.
+	FS fs.FS
+	// Dir is the directory of FS holding keys. If Dir is empty, it is the root of FS (".").
+	Dir string
+	// Normalize, if not nil, converts file names to keys (e.g., strings.ToUpper to match
+	// DB_PASSWORD to a file named db_password). If two file names normalize to the same key, the
+	// first, in lexical order, is used.
+	Normalize func(name string) string
+}
+
+var (
+	_ Multienv  = FSEnv{}
+	_ KeyLister = FSEnv{}
+)
+
+// DirEnv returns an FSEnv for the directory dir of the operating system.
+func DirEnv(dir string) FSEnv {
+	return FSEnv{FS: os.DirFS(dir)}
+}
+
+func (e FSEnv) dir() string {
+	if e.Dir == "" {
+		return "."
+	}
+	return e.Dir
+}
+
+func (e FSEnv) path(name string) string {
+	if e.Dir == "" || e.Dir == "." {
+		return name
+	}
+	return e.Dir + "/" + name
+}
+
+func (e FSEnv) key(name string) string {
+	if e.Normalize == nil {
+		return name
+	}
+	return e.Normalize(name)
+}
+
+// names returns the names of the files in the FSEnv's directory that are keys, in lexical order.
+func (e FSEnv) names() ([]string, error) {
+	ents, err := fs.ReadDir(e.FS, e.dir())
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(ents))
+	for _, ent := range ents {
+		name := ent.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		// Stat follows symlinks, so directories behind them are excluded.
+		if fi, err := fs.Stat(e.FS, e.path(name)); err != nil || fi.IsDir() {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// file returns the name of the file holding key.
+func (e FSEnv) file(key string) (string, error) {
+	if e.Normalize == nil {
+		if key == "" || strings.HasPrefix(key, ".") || !fs.ValidPath(key) || strings.Contains(key, "/") {
+			return "", NoValueError(key)
+		}
+		return key, nil
+	}
+
+	names, err := e.names()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if e.key(name) == key {
+			return name, nil
+		}
+	}
+	return "", NoValueError(key)
+}
+
+// Getenv implements Env.
+func (e FSEnv) Getenv(key string) (string, error) {
+	name, err := e.file(key)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := fs.ReadFile(e.FS, e.path(name))
+	if errors.Is(err, fs.ErrNotExist) || isDirErr(e.FS, e.path(name), err) {
+		return "", NoValueError(key)
+	} else if err != nil {
+		return "", newKeyError(key, err)
+	}
+	return trimNewline(string(p)), nil
+}
+
+// GetenvAll implements Multienv. It returns each line of the file holding key. An empty file holds
+// a single empty value.
+func (e FSEnv) GetenvAll(key string) ([]string, error) {
+	v, err := e.Getenv(key)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(v, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines, nil
+}
+
+// Keys implements KeyLister.
+func (e FSEnv) Keys(prefix string) ([]string, error) {
+	names, err := e.names()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := e.key(name)
+		if seen[key] || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// isDirErr returns whether err is the result of reading the directory at name.
+func isDirErr(fsys fs.FS, name string, err error) bool {
+	if err == nil {
+		return false
+	}
+	fi, serr := fs.Stat(fsys, name)
+	return serr == nil && fi.IsDir()
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSEnv(t *testing.T) {
+	env := FSEnv{
+		FS: fstest.MapFS{
+			"conf/db_user":         {Data: []byte("admin\n")},
+			"conf/db_password":     {Data: []byte("hunter2\r\n")},
+			"conf/db_hosts":        {Data: []byte("a\nb\r\nc\n")},
+			"conf/empty":           {Data: nil},
+			"conf/.hidden":         {Data: []byte("x")},
+			"conf/sub/db_port":     {Data: []byte("5432")},
+			"conf/..2024_01_01/x":  {Data: []byte("x")},
+			"other/db_unreachable": {Data: []byte("x")},
+		},
+		Dir:       "conf",
+		Normalize: strings.ToUpper,
+	}
+
+	getenv := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"DB_USER", "admin", true},
+		{"DB_PASSWORD", "hunter2", true},
+		{"EMPTY", "", true},
+		{"db_user", "", false},
+		{".HIDDEN", "", false},
+		{"SUB", "", false},
+		{"DB_UNREACHABLE", "", false},
+	}
+	for _, c := range getenv {
+		got, err := env.Getenv(c.key)
+		if c.ok && err != nil {
+			t.Errorf("Getenv(%q) error = %v", c.key, err)
+		} else if !c.ok && !IsNoValue(err) {
+			t.Errorf("Getenv(%q) error = %v; want no value", c.key, err)
+		} else if got != c.want {
+			t.Errorf("Getenv(%q) = %q; want %q", c.key, got, c.want)
+		}
+	}
+
+	if got, err := env.GetenvAll("DB_HOSTS"); err != nil {
+		t.Errorf("GetenvAll() error = %v", err)
+	} else if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetenvAll() = %q; want %q", got, want)
+	}
+
+	if got, err := env.Keys("DB_"); err != nil {
+		t.Errorf("Keys() error = %v", err)
+	} else if want := []string{"DB_HOSTS", "DB_PASSWORD", "DB_USER"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %q; want %q", got, want)
+	}
+}
+
+func TestDirEnvAtomicWriter(t *testing.T) {
+	// Mimic the layout of a Kubernetes volume: files are held in a timestamped directory, and
+	// each key is a symlink through the ..data symlink.
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..2024_01_01_00_00_00.000000000")
+	if err := os.Mkdir(data, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data, "PORT"), []byte("8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Base(data), filepath.Join(dir, "..data")); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "PORT"), filepath.Join(dir, "PORT")); err != nil {
+		t.Fatal(err)
+	}
+
+	env := DirEnv(dir)
+	if keys, err := env.Keys(""); err != nil {
+		t.Fatalf("Keys() error = %v", err)
+	} else if want := []string{"PORT"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("Keys() = %q; want %q", keys, want)
+	}
+
+	var port int
+	r := Reader{Source: env}
+	if err := r.Getenv(&port, "PORT"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	} else if port != 8080 {
+		t.Fatalf("Getenv() = %d; want 8080", port)
+	}
+}
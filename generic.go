@@ -0,0 +1,41 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+// Get loads the value of the environment variable key into a new T using r and returns it. If r
+// is nil, the DefaultReader is used. If key has no value, Get returns the zero value of T and
+// a no-value error. See Reader.Getenv for more information.
+func Get[T any](r *Reader, key string) (T, error) {
+	var v T
+	err := readerOrDefault(r).Getenv(&v, key)
+	return v, err
+}
+
+// GetOr is the same as Get, except that if key has no value, it returns def and no error.
+func GetOr[T any](r *Reader, key string, def T) (T, error) {
+	v, err := Get[T](r, key)
+	if IsNoValue(err) {
+		return def, nil
+	}
+	return v, err
+}
+
+// Parse loads the fields of a new T, usually a struct, from the environment variables beginning
+// with prefix using r and returns it. If r is nil, the DefaultReader is used. Unlike Get, a T with
+// no values in the environment is not an error, so that a struct holding only defaults can be
+// parsed. Missing required fields are still reported.
+func Parse[T any](r *Reader, prefix string) (T, error) {
+	v, err := Get[T](r, prefix)
+	if IsNoValue(err) {
+		err = nil
+	}
+	return v, err
+}
+
+func readerOrDefault(r *Reader) *Reader {
+	if r == nil {
+		return DefaultReader
+	}
+	return r
+}
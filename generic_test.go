@@ -0,0 +1,67 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	r := &Reader{Source: Values{"PORT": {"8080"}, "BAD": {"x"}}, Sep: "_"}
+
+	if got, err := Get[int](r, "PORT"); err != nil || got != 8080 {
+		t.Errorf("Get() = %d, %v; want 8080, <nil>", got, err)
+	}
+	if got, err := Get[int](r, "MISSING"); !IsNoValue(err) || got != 0 {
+		t.Errorf("Get() = %d, %v; want 0, no value", got, err)
+	}
+	if _, err := Get[int](r, "BAD"); err == nil || IsNoValue(err) {
+		t.Errorf("Get() error = %v; want syntax error", err)
+	}
+
+	if got, err := GetOr(r, "PORT", 80); err != nil || got != 8080 {
+		t.Errorf("GetOr() = %d, %v; want 8080, <nil>", got, err)
+	}
+	if got, err := GetOr(r, "MISSING", time.Second); err != nil || got != time.Second {
+		t.Errorf("GetOr() = %v, %v; want 1s, <nil>", got, err)
+	}
+	if _, err := GetOr(r, "BAD", 80); err == nil || IsNoValue(err) {
+		t.Errorf("GetOr() error = %v; want syntax error", err)
+	}
+}
+
+func TestGetDefaultReader(t *testing.T) {
+	t.Setenv("ENVI_GET_TEST", "a b")
+	if got, err := Get[[]string](nil, "ENVI_GET_TEST"); err != nil || !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Get() = %q, %v; want [a b], <nil>", got, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	type config struct {
+		Host string `envi:",default=localhost"`
+		Port int    `envi:",required"`
+	}
+
+	r := &Reader{Source: Values{"APP_Port": {"5432"}}, Sep: "_"}
+	got, err := Parse[config](r, "APP")
+	if want := (config{Host: "localhost", Port: 5432}); err != nil || got != want {
+		t.Errorf("Parse() = %+v, %v; want %+v, <nil>", got, err, want)
+	}
+
+	r = &Reader{Source: Values{}, Sep: "_"}
+	if _, err := Parse[config](r, "APP"); !errors.Is(err, ErrRequired) {
+		t.Errorf("Parse() error = %v; want ErrRequired", err)
+	}
+
+	type optional struct {
+		Host string `envi:",default=localhost"`
+	}
+	if got, err := Parse[optional](r, "APP"); err != nil || got.Host != "localhost" {
+		t.Errorf("Parse() = %+v, %v; want localhost, <nil>", got, err)
+	}
+}
@@ -1 +1,3 @@
-module "github.com/Kochava/envi"
+module github.com/Kochava/envi
+
+go 1.21
@@ -45,7 +45,9 @@ func indirect(v reflect.Value) reflect.Value {
 	// NOTE: this function is modified from the encoding/json stdlib package to do more or less
 	// the same thing except without handling cases that envi doesn't support (e.g., null).
 
-	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+	if pointerTypes[v.Type()] && v.CanAddr() {
+		return v.Addr()
+	} else if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
 		v = v.Addr()
 	}
 	for {
@@ -53,6 +55,10 @@ func indirect(v reflect.Value) reflect.Value {
 			break
 		}
 		typ := v.Type()
+		// Stop at pointers that are assigned rather than allocated (e.g., *os.File).
+		if pointerTypes[typ.Elem()] {
+			return v
+		}
 		if v.IsNil() {
 			v.Set(reflect.New(typ.Elem()))
 		}
@@ -0,0 +1,122 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const fdScheme = "fd://"
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation.
+const listenFDsStart = 3
+
+var errNoListenFD = errors.New("no socket with that name was passed by systemd")
+
+// inheritedFiles holds the files opened for inherited file descriptors, so that each descriptor
+// has a single *os.File. Files are never closed, since closing one (including by its finalizer)
+// would close the descriptor for every value loaded from it. Values loaded from these files hold
+// duplicate descriptors instead.
+var inheritedFiles struct {
+	sync.Mutex
+	files map[uintptr]*os.File
+}
+
+// inheritedFile returns the file for the inherited file descriptor fd.
+func inheritedFile(fd uintptr) *os.File {
+	inheritedFiles.Lock()
+	defer inheritedFiles.Unlock()
+	if f := inheritedFiles.files[fd]; f != nil {
+		return f
+	}
+	if inheritedFiles.files == nil {
+		inheritedFiles.files = make(map[uintptr]*os.File)
+	}
+	f := os.NewFile(fd, fdScheme+strconv.FormatUint(uint64(fd), 10))
+	inheritedFiles.files[fd] = f
+	return f
+}
+
+// listenFD returns the file descriptor passed by systemd socket activation with the given name
+// (from LISTEN_FDNAMES), or ok=false if there is none. Descriptors are only considered passed if
+// LISTEN_PID is the current process's PID.
+func listenFD(name string) (fd uintptr, ok bool) {
+	if name == "" {
+		return 0, false
+	} else if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return 0, false
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	for i, fdname := range strings.Split(os.Getenv("LISTEN_FDNAMES"), ":") {
+		if i < n && fdname == name {
+			return uintptr(listenFDsStart + i), true
+		}
+	}
+	return 0, false
+}
+
+// parseFD parses the file descriptor of an fd:// address, which is either a number or the name of
+// a socket passed by systemd socket activation.
+func parseFD(val string) (uintptr, error) {
+	ref := strings.TrimPrefix(val, fdScheme)
+	if ref == "" {
+		return 0, mksyntaxerr(val, strconv.ErrSyntax)
+	} else if fd, err := strconv.ParseUint(ref, 10, 31); err == nil {
+		return uintptr(fd), nil
+	} else if ref[0] >= '0' && ref[0] <= '9' {
+		return 0, mksyntaxerr(val, err)
+	} else if fd, ok := listenFD(ref); ok {
+		return fd, nil
+	}
+	return 0, fmt.Errorf("%s: %w", val, errNoListenFD)
+}
+
+// loadListener loads a net.Listener from val, which is one of:
+//
+//   tcp://host:port, tcp4://host:port, or tcp6://host:port to listen on a TCP address
+//   unix:///path/to/socket or unix://@name to listen on a Unix socket or abstract socket
+//   fd://N to listen on the inherited file descriptor N
+//   fd://name to listen on the socket named name by systemd socket activation
+//   host:port to listen on a TCP address
+func loadListener(out *net.Listener, val string) error {
+	if val == "" {
+		return ErrNoValue
+	}
+
+	var (
+		l   net.Listener
+		err error
+	)
+	switch i := strings.Index(val, "://"); {
+	case strings.HasPrefix(val, fdScheme):
+		var fd uintptr
+		if fd, err = parseFD(val); err != nil {
+			return err
+		}
+		l, err = net.FileListener(inheritedFile(fd))
+	case i == -1:
+		l, err = net.Listen("tcp", val)
+	default:
+		switch network, addr := val[:i], val[i+3:]; network {
+		case "tcp", "tcp4", "tcp6", "unix", "unixpacket":
+			l, err = net.Listen(network, addr)
+		default:
+			return mksyntaxerr(val, fmt.Errorf("unsupported network %q", network))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	*out = l
+	return nil
+}
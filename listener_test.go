@@ -0,0 +1,118 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetenvListener(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	cases := []struct {
+		in      string
+		network string
+	}{
+		{"tcp://127.0.0.1:0", "tcp"},
+		{"127.0.0.1:0", "tcp"},
+		{"unix://" + sock, "unix"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.in, func(t *testing.T) {
+			if c.network == "unix" && runtime.GOOS == "windows" {
+				t.Skip("unix sockets are not supported")
+			}
+			var l net.Listener
+			r := Reader{Source: Values{"LISTEN": {c.in}}}
+			if err := r.Getenv(&l, "LISTEN"); err != nil {
+				t.Fatalf("Getenv() error = %v", err)
+			}
+			defer l.Close()
+			if got := l.Addr().Network(); got != c.network {
+				t.Fatalf("Getenv() network = %q; want %q", got, c.network)
+			}
+		})
+	}
+
+	for _, in := range []string{"udp://:0", "fd://", "fd://3x", "fd://nosuchname"} {
+		var l net.Listener
+		r := Reader{Source: Values{"LISTEN": {in}}}
+		if err := r.Getenv(&l, "LISTEN"); err == nil || IsNoValue(err) {
+			t.Errorf("Getenv(%q) error = %v; want error", in, err)
+		}
+	}
+}
+
+// TestGetenvListenerFD passes a listening socket to a child process, as systemd socket activation
+// would, and checks that the child loads it by number and by name.
+func TestGetenvListenerFD(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("file listeners are not supported")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestListenerFDChild$", "-test.v")
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.Env = append(os.Environ(),
+		"ENVI_TEST_LISTENER_CHILD="+l.Addr().String(),
+		"LISTEN_FDS=1",
+		"LISTEN_FDNAMES=web",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child failed: %v\n%s", err, out)
+	} else if !strings.Contains(string(out), "--- PASS: TestListenerFDChild") {
+		t.Fatalf("child did not pass:\n%s", out)
+	}
+}
+
+func TestListenerFDChild(t *testing.T) {
+	addr := os.Getenv("ENVI_TEST_LISTENER_CHILD")
+	if addr == "" {
+		t.Skip("only run as a child of TestGetenvListenerFD")
+	}
+	// systemd sets LISTEN_PID before exec'ing the service, which the parent can't do.
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	var byNumber net.Listener
+	r := Reader{Source: Values{"LISTEN": {"fd://3"}}, Sep: "_"}
+	if err := r.Getenv(&byNumber, "LISTEN"); err != nil {
+		t.Fatalf("Getenv(fd://3) error = %v", err)
+	}
+	defer byNumber.Close()
+	if got := byNumber.Addr().String(); got != addr {
+		t.Fatalf("Getenv(fd://3) addr = %s; want %s", got, addr)
+	}
+
+	var config struct {
+		Web   net.Listener `envi:",fdname=web"`
+		Admin net.Listener `envi:",fdname=admin"`
+	}
+	r.Source = Values{}
+	if err := r.Getenv(&config, "APP"); err != nil {
+		t.Fatalf("Getenv(fdname=web) error = %v", err)
+	}
+	defer config.Web.Close()
+	if got := config.Web.Addr().String(); got != addr {
+		t.Fatalf("Getenv(fdname=web) addr = %s; want %s", got, addr)
+	} else if config.Admin != nil {
+		t.Fatalf("Getenv(fdname=admin) = %v; want nil", config.Admin.Addr())
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NameMapper is an interface used by a Reader to convert the names of struct fields into the
+// environment variable suffixes used to load them. It is only used for fields whose tag does not
+// name them.
+type NameMapper interface {
+	MapName(name string) string
+}
+
+// NameMapperFunc is a callback NameMapper.
+type NameMapperFunc func(name string) string
+
+var _ NameMapper = NameMapperFunc(nil)
+
+// MapName invokes the underlying callback with the given name. This implements NameMapper.
+func (fn NameMapperFunc) MapName(name string) string {
+	return fn(name)
+}
+
+type nameCase int
+
+// Built-in NameMappers.
+const (
+	// Verbatim uses field names as-is. This is the default.
+	Verbatim nameCase = iota
+	// UpperSnake converts field names to UPPER_SNAKE_CASE. Runs of capitals are treated as
+	// acronyms, so HTTPTimeout is converted to HTTP_TIMEOUT and UserID to USER_ID.
+	UpperSnake
+	// LowerSnake converts field names to lower_snake_case, the same as UpperSnake but in lower
+	// case (e.g., HTTPTimeout is converted to http_timeout).
+	LowerSnake
+)
+
+var _ NameMapper = Verbatim
+
+// MapName converts name to the receiver's case. This implements NameMapper.
+func (c nameCase) MapName(name string) string {
+	switch c {
+	case UpperSnake:
+		return strings.ToUpper(snakeCase(name))
+	case LowerSnake:
+		return strings.ToLower(snakeCase(name))
+	}
+	return name
+}
+
+// snakeCase inserts underscores between the words of a mixed-case name. A new word begins at an
+// upper case letter following a lower case letter or digit, or at the last upper case letter of a
+// run of them when it's followed by a lower case letter (e.g., the T in HTTPTimeout).
+func snakeCase(name string) string {
+	var (
+		b     strings.Builder
+		runes = []rune(name)
+	)
+	b.Grow(len(name) + 4)
+	for i, c := range runes {
+		if i > 0 && unicode.IsUpper(c) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
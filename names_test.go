@@ -0,0 +1,82 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNameMappers(t *testing.T) {
+	cases := []struct {
+		in, upper, lower string
+	}{
+		{"", "", ""},
+		{"Name", "NAME", "name"},
+		{"MaxConns", "MAX_CONNS", "max_conns"},
+		{"HTTPTimeout", "HTTP_TIMEOUT", "http_timeout"},
+		{"UserID", "USER_ID", "user_id"},
+		{"ID", "ID", "id"},
+		{"Base64Data", "BASE64_DATA", "base64_data"},
+		{"Max_Conns", "MAX_CONNS", "max_conns"},
+		{"already_snake", "ALREADY_SNAKE", "already_snake"},
+		{"TLSConfigV2", "TLS_CONFIG_V2", "tls_config_v2"},
+	}
+
+	for _, c := range cases {
+		if got := UpperSnake.MapName(c.in); got != c.upper {
+			t.Errorf("UpperSnake.MapName(%q) = %q; want %q", c.in, got, c.upper)
+		}
+		if got := LowerSnake.MapName(c.in); got != c.lower {
+			t.Errorf("LowerSnake.MapName(%q) = %q; want %q", c.in, got, c.lower)
+		}
+		if got := Verbatim.MapName(c.in); got != c.in {
+			t.Errorf("Verbatim.MapName(%q) = %q; want %q", c.in, got, c.in)
+		}
+	}
+}
+
+func TestGetenvNameMapper(t *testing.T) {
+	type Config struct {
+		MaxConns    int
+		HTTPTimeout int
+		Tagged      int `envi:"tagged"`
+	}
+
+	env := Values{
+		"APP_MAX_CONNS":    {"1"},
+		"APP_HTTP_TIMEOUT": {"2"},
+		"APP_tagged":       {"3"},
+		"app-maxconns":     {"4"},
+		"app-httptimeout":  {"5"},
+	}
+
+	cases := []struct {
+		name  string
+		key   string
+		sep   string
+		names NameMapper
+		want  Config
+	}{
+		{"UpperSnake", "APP", "_", UpperSnake, Config{1, 2, 3}},
+		{"Func", "app", "-", NameMapperFunc(strings.ToLower), Config{MaxConns: 4, HTTPTimeout: 5}},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				r   = Reader{Source: env, Sep: c.sep, Names: c.names}
+				got Config
+			)
+			if err := r.Getenv(&got, c.key); err != nil {
+				t.Fatalf("Getenv() err = %v; want nil", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Getenv() = %+v; want %+v", got, c.want)
+			}
+		})
+	}
+}
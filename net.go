@@ -0,0 +1,151 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+var (
+	errInvalidIP       = errors.New("invalid IP address")
+	errInvalidHostPort = errors.New("invalid host:port address")
+)
+
+func loadIP(out *net.IP, val string) error {
+	if val == "" {
+		return ErrNoValue
+	}
+	ip := net.ParseIP(val)
+	if ip == nil {
+		return mksyntaxerr(val, errInvalidIP)
+	}
+	*out = ip
+	return nil
+}
+
+func loadIPNet(out *net.IPNet, val string) error {
+	if val == "" {
+		return ErrNoValue
+	}
+	_, ipnet, err := net.ParseCIDR(val)
+	if err != nil {
+		return mksyntaxerr(val, err)
+	}
+	*out = *ipnet
+	return nil
+}
+
+func loadIPNetIndirect(out **net.IPNet, val string) error {
+	if val == "" {
+		return ErrNoValue
+	}
+	_, ipnet, err := net.ParseCIDR(val)
+	if err != nil {
+		return mksyntaxerr(val, err)
+	}
+	*out = ipnet
+	return nil
+}
+
+func loadHardwareAddr(out *net.HardwareAddr, val string) error {
+	if val == "" {
+		return ErrNoValue
+	}
+	mac, err := net.ParseMAC(val)
+	if err != nil {
+		return mksyntaxerr(val, err)
+	}
+	*out = mac
+	return nil
+}
+
+func loadNetipAddr(out *netip.Addr, val string) (err error) {
+	if val == "" {
+		return ErrNoValue
+	}
+	if *out, err = netip.ParseAddr(val); err != nil {
+		return mksyntaxerr(val, err)
+	}
+	return nil
+}
+
+func loadNetipPrefix(out *netip.Prefix, val string) (err error) {
+	if val == "" {
+		return ErrNoValue
+	}
+	if *out, err = netip.ParsePrefix(val); err != nil {
+		return mksyntaxerr(val, err)
+	}
+	return nil
+}
+
+func loadNetipAddrPort(out *netip.AddrPort, val string) (err error) {
+	if val == "" {
+		return ErrNoValue
+	}
+	if *out, err = netip.ParseAddrPort(val); err != nil {
+		return mksyntaxerr(val, err)
+	}
+	return nil
+}
+
+// HostPort is a network address of the form host:port, such as those accepted by net.Dial. Unlike
+// netip.AddrPort, its host may be a name. The host may also be empty (e.g., ":8080") to listen on
+// all addresses. The port must be a number.
+type HostPort struct {
+	Host string
+	Port uint16
+}
+
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host, strconv.Itoa(int(hp.Port)))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (hp HostPort) MarshalText() ([]byte, error) {
+	return []byte(hp.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It returns a *SyntaxError if text is not
+// a valid host:port address.
+func (hp *HostPort) UnmarshalText(text []byte) error {
+	val := string(text)
+	host, port, err := net.SplitHostPort(val)
+	if err != nil {
+		return mksyntaxerr(val, err)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || !validHost(host) {
+		return mksyntaxerr(val, errInvalidHostPort)
+	}
+	*hp = HostPort{Host: host, Port: uint16(p)}
+	return nil
+}
+
+// validHost returns whether host is empty, an IP address, or a DNS name.
+func validHost(host string) bool {
+	if host == "" {
+		return true
+	} else if strings.Contains(host, ":") {
+		_, err := netip.ParseAddr(host)
+		return err == nil
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			switch c := label[i]; {
+			case c == '-', c == '_', '0' <= c && c <= '9', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
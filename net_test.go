@@ -0,0 +1,130 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func mustCIDR(s string) *net.IPNet {
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return ipnet
+}
+
+func TestGetenvNetTypes(t *testing.T) {
+	type config struct {
+		IP        net.IP
+		IPs       []net.IP
+		Net       *net.IPNet
+		Nets      []*net.IPNet
+		NetValue  net.IPNet
+		MAC       net.HardwareAddr
+		Addr      netip.Addr
+		Prefixes  []netip.Prefix
+		AddrPort  netip.AddrPort
+		Listen    HostPort
+		Upstreams []HostPort
+	}
+
+	r := Reader{Source: Values{
+		"test_IP":        {"10.0.0.1"},
+		"test_IPs":       {"::1", "127.0.0.1"},
+		"test_Net":       {"10.0.0.0/8"},
+		"test_Nets":      {"10.0.0.0/8", "fd00::/8"},
+		"test_NetValue":  {"192.168.0.0/16"},
+		"test_MAC":       {"00:00:5e:00:53:01"},
+		"test_Addr":      {"fe80::1%eth0"},
+		"test_Prefixes":  {"10.0.0.0/8", "fd00::/8"},
+		"test_AddrPort":  {"[::1]:53"},
+		"test_Listen":    {":8080"},
+		"test_Upstreams": {"db.internal:5432", "[::1]:6379"},
+	}, Sep: "_"}
+
+	var got config
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+
+	mac, _ := net.ParseMAC("00:00:5e:00:53:01")
+	want := config{
+		IP:       net.ParseIP("10.0.0.1"),
+		IPs:      []net.IP{net.ParseIP("::1"), net.ParseIP("127.0.0.1")},
+		Net:      mustCIDR("10.0.0.0/8"),
+		Nets:     []*net.IPNet{mustCIDR("10.0.0.0/8"), mustCIDR("fd00::/8")},
+		NetValue: *mustCIDR("192.168.0.0/16"),
+		MAC:      mac,
+		Addr:     netip.MustParseAddr("fe80::1%eth0"),
+		Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")},
+		AddrPort: netip.MustParseAddrPort("[::1]:53"),
+		Listen:   HostPort{Port: 8080},
+		Upstreams: []HostPort{
+			{Host: "db.internal", Port: 5432},
+			{Host: "::1", Port: 6379},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Getenv() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Round trip
+	vals, err := r.Marshal(got, "test")
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var again config
+	r.Source = vals
+	if err := r.Getenv(&again, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	} else if !reflect.DeepEqual(again, want) {
+		t.Fatalf("Getenv() round trip =\n%+v\nwant\n%+v", again, want)
+	}
+}
+
+func TestGetenvNetTypesInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		dst  interface{}
+		in   []string
+		bad  string
+	}{
+		{"IP", new(net.IP), []string{"10.0.0.256"}, "10.0.0.256"},
+		{"IPs", new([]net.IP), []string{"10.0.0.1", "nope"}, "nope"},
+		{"IPNet", new(*net.IPNet), []string{"10.0.0.0/33"}, "10.0.0.0/33"},
+		{"HardwareAddr", new(net.HardwareAddr), []string{"00:00"}, "00:00"},
+		{"Addr", new(netip.Addr), []string{"::g"}, "::g"},
+		{"Prefixes", new([]netip.Prefix), []string{"10.0.0.0/8", "10.0.0.0"}, "10.0.0.0"},
+		{"AddrPort", new(netip.AddrPort), []string{"localhost:80"}, "localhost:80"},
+		{"HostPortNoPort", new(HostPort), []string{"localhost"}, "localhost"},
+		{"HostPortName", new(HostPort), []string{"localhost:http"}, "localhost:http"},
+		{"HostPortRange", new(HostPort), []string{"localhost:65536"}, "localhost:65536"},
+		{"HostPortHost", new(HostPort), []string{"bad host:80"}, "bad host:80"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			r := Reader{Source: Values{"test": c.in}}
+			err := r.Getenv(c.dst, "test")
+			var se *SyntaxError
+			if !errors.As(err, &se) || se.Str != c.bad {
+				t.Fatalf("Getenv() error = %v; want SyntaxError for %q", err, c.bad)
+			}
+		})
+	}
+}
+
+func TestGetenvNetTypesEmpty(t *testing.T) {
+	r := Reader{Source: Values{"test": {""}}}
+	for _, dst := range []interface{}{new(net.IP), new(*net.IPNet), new(net.HardwareAddr), new(netip.Prefix), new(HostPort)} {
+		if err := r.Getenv(dst, "test"); !IsNoValue(err) {
+			t.Errorf("Getenv(%T) error = %v; want no value", dst, err)
+		}
+	}
+}
@@ -6,9 +6,13 @@ package envi
 import (
 	"encoding"
 	"fmt"
+	"io"
+	"net"
+	"net/netip"
 	"net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +24,10 @@ const DefaultMaxSliceLen = 1000
 // DefaultMaxDepth is the default maximum depth of parsing nested struct values.
 const DefaultMaxDepth = 8
 
+// DefaultFileSuffix is the suffix of file variables for struct fields with the file flag when
+// Reader.FileSuffix is empty.
+const DefaultFileSuffix = "_FILE"
+
 // DefaultReader is the default envi *Reader used by the Getenv and Load functions. It may be
 // changed, but it is not safe to modify it while in use.
 var DefaultReader = &Reader{Sep: "_"}
@@ -27,13 +35,18 @@ var DefaultReader = &Reader{Sep: "_"}
 // Reader defines the instance type of an envi unmarshaler.
 type Reader struct {
 	// Source provides environment variable values. If Source is also a Multienv, its GetenvAll implementation is
-	// used in place of Split to get multiple values from the environment.
+	// used in place of Split to get multiple values from the environment (for a Layered source, such as a Chain,
+	// only if the layer holding the value is a Multienv).
 	Source Env
 	// Split is used to split a value from Source into multiple values. If Source is a Multienv, Split is unused.
 	Split Splitter
 	// Sep is the separator used to join the prefix key and field names when unmarshaling
 	// structs (and only structs).
 	Sep string
+	// Names converts struct field names to environment variable suffixes for fields whose tag
+	// does not name them (e.g., UpperSnake converts MaxConns to MAX_CONNS). If Names is nil, field
+	// names are used verbatim.
+	Names NameMapper
 	// MaxSliceLen is the maximum number of environment variables that will be checked for slice
 	// values. If MaxSliceLen is less than 1, this defaults to DefaultMaxSliceLen.
 	MaxSliceLen int
@@ -41,6 +54,52 @@ type Reader struct {
 	// encountered that does not have known keys, field parsing is abandoned after this depth.
 	// If MaxDepth is less than 1, it defaults to DefaultMaxDepth.
 	MaxDepth int
+	// DefaultEmpty controls whether struct fields with a default (see the default flag under Load)
+	// are also assigned their default when their environment variable is set but empty. Otherwise,
+	// only fields that would have no value are assigned their default.
+	DefaultEmpty bool
+	// AllErrors controls whether loading a struct continues past fields that fail to load. If
+	// true, Getenv and Load return Errors, holding a *KeyError for each failed field (including
+	// missing required fields), instead of returning the first error encountered.
+	AllErrors bool
+	// FileSuffix enables reading values from files for all keys. If a key has no value but the key
+	// followed by FileSuffix (its file variable, e.g., DB_PASSWORD_FILE for DB_PASSWORD) does, the
+	// contents of the file it names are used as the key's value, minus a trailing newline. It is an
+	// error for both the key and its file variable to have values. If FileSuffix is empty, only
+	// struct fields with the file flag (see Load) read files, using DefaultFileSuffix.
+	FileSuffix string
+	// Expand enables expanding variable references in values from Source and in struct field
+	// defaults. References are resolved using Source, and their values are expanded in turn. The
+	// following forms are supported:
+	//
+	//   $VAR or ${VAR}    the value of VAR, or the empty string if VAR is unset
+	//   ${VAR:-default}   the value of VAR, or default if VAR is unset or empty
+	//   ${VAR:?message}   the value of VAR, or an ErrExpandUnset error with message if VAR is
+	//                     unset or empty
+	//   $$                a literal $
+	//
+	// A $ that does not begin a reference is kept as-is. References that refer back to the value
+	// being expanded return an ErrExpandCycle error. Values read from files are not expanded.
+	Expand bool
+	// Decoders maps types to functions that decode them from environment variable text (see
+	// also Register). Decoders are consulted before envi's built-in decoding, including
+	// Unmarshaler and encoding.TextUnmarshaler, for values of the type and pointers to it,
+	// wherever they occur (e.g., struct fields, slice elements, and map keys and values). Types
+	// with decoders are split like scalars when loaded as slice elements. Empty values are treated
+	// as having no value and are not passed to decoders.
+	Decoders map[reflect.Type]DecodeFunc
+	// ExtendedDurations enables an extended syntax for time.Duration values (see also the extended
+	// flag under Load). In addition to the syntax of time.ParseDuration, durations may use the
+	// units d (24h) and w (7d), such as 30d or 1w2d, or be ISO 8601 durations, such as PT15M or
+	// P1DT12H. ISO 8601 years and months are rejected, since their lengths vary.
+	ExtendedDurations bool
+	// Atomic controls whether Getenv and Load leave dst untouched when they return an error (other
+	// than a no-value error). If true, values are loaded into a copy of the value dst points to,
+	// which is assigned to dst only once loading succeeds. If loading fails, io.Closers in the copy
+	// that weren't already in dst are closed (e.g., files opened by an Unmarshaler). Only exported
+	// struct fields are copied deeply, so types that an Unmarshaler modifies through unexported
+	// pointers, slices, or maps may still be modified.
+	Atomic bool
 }
 
 // Getenv attempts to load the value held by the environment variable key into dst. If an error
@@ -57,7 +116,8 @@ type Reader struct {
 // Decoding rules for structs and slices are described under (*Reader).Load.
 func (r *Reader) Getenv(dst interface{}, key string) (err error) {
 	defer swallowLoadPanic("Getenv", key, &err)
-	return r.readState().loadFromEnv(dst, key)
+	rs := r.readState()
+	return rs.loadDst(dst, key, func(dst interface{}) error { return rs.loadFromEnv(dst, key) })
 }
 
 // Load attempts to parse the given value (identified as key, which is occasionally relevant when
@@ -66,10 +126,12 @@ func (r *Reader) Getenv(dst interface{}, key string) (err error) {
 // source, as it's unused inside of Load.
 //
 // If dst is a slice and an error occurs, dst is still assigned the value of partially unmarshaling
-// the slice (by default using strings.Fields to separate slice values in the environment variable).
+// the slice (by default using strings.Fields to separate slice values in the environment variable),
+// and struct fields loaded before the error keep their new values, unless the Reader is Atomic.
 // This is only relevant if, for example, you unmarshal a slice of files, connections, listeners, or
-// something else that must be closed, as Getenv will not close anything that is the result of
-// incorrectly unmarshaling a slice.
+// something else that must be closed. Files and listeners opened by envi itself are closed when
+// Getenv or Load return an error (other than a no-value error), but Getenv will not close anything
+// else that is the result of incorrectly unmarshaling a slice (see Reader.Atomic).
 //
 // Struct field decoding can be configured by using the 'envi' field tag. The first value of a field
 // tagis always the name of the environment variable suffix, after a separator. Subsequent fields
@@ -88,32 +150,277 @@ func (r *Reader) Getenv(dst interface{}, key string) (err error) {
 //       SkipField int `envi:"-"`
 //       // Ignore errors on this field
 //       QuietField int `envi:",quiet"
+//       // Use 8080 if ${Prefix}${Sep}DefaultField has no value
+//       DefaultField int `envi:",default=8080"`
+//       // Return an error if ${Prefix}${Sep}RequiredField has no value
+//       RequiredField int `envi:",required"`
+//       // Read the file named by ${Prefix}${Sep}FileField_FILE if ${Prefix}${Sep}FileField is unset
+//       FileField string `envi:",file"`
+//       // Return an error unless ${Prefix}${Sep}StrictField holds exactly 3 values
+//       StrictField [3]int `envi:",strict"`
+//       // Accept sizes such as 512MiB in ${Prefix}${Sep}UnitField
+//       UnitField int64 `envi:",unit=bytes"`
+//       // Parse ${Prefix}${Sep}LayoutField as a date, such as 2024-06-01
+//       LayoutField time.Time `envi:",layout=DateOnly"`
+//       // Accept 30d or PT15M in ${Prefix}${Sep}ExtendedField, and treat 30 as 30s
+//       ExtendedField time.Duration `envi:",extended,unit=s"`
+//       // Use the socket named web by systemd if ${Prefix}${Sep}Listener has no value
+//       Listener net.Listener `envi:",fdname=web"`
+//       // Append to the file named by ${Prefix}${Sep}LogFile, creating it with mode 0640
+//       LogFile io.Writer `envi:",mode=a,perm=0640"`
+//       // Return an error unless ${Prefix}${Sep}Port is between 1 and 65535
+//       Port int `envi:",min=1,max=65535"`
+//       // Return an error unless ${Prefix}${Sep}Level is debug, info, or error
+//       Level string `envi:",oneof=debug|info|error"`
 //   }
 //
 // Flags may be specified in any order, and the last flag seen of its type is the one that is used.
 // The quiet flag is used to ignore unmarshaling failures.
-// The sep flag sets a custom separator -- this does not allow commas.
+// The sep flag sets a custom separator.
+// The default flag sets text to load into the field, as if it were the field's value, when the
+// field would otherwise have no value (see also Reader.DefaultEmpty). Defaults are split using
+// Split, even if Source is a Multienv. Fields that only receive defaults do not count as set, so
+// Getenv still returns a no-value error if nothing else in a struct has a value.
+// The required flag causes Getenv and Load to return a *MissingError, listing the keys of all
+// required fields that have no value and no default, in place of a no-value error. Required fields
+// of a slice element that has no value at all are not reported, since the element is absent.
+// The file flag enables reading the field's value from a file, as described under
+// Reader.FileSuffix, using DefaultFileSuffix if the Reader has no FileSuffix. Values read from files
+// are split using Split, even if Source is a Multienv.
+// The strict flag causes arrays in the field to return an ErrArrayLength error unless they receive
+// exactly as many values as their length, rather than only when they receive more.
+// The unit flag allows integer and float fields (and their slice elements and map values) to have
+// a unit suffix: unit=bytes accepts the byte sizes described under ByteSize, and unit=si accepts
+// the SI suffixes k, M, G, T, P, and E (e.g., 10k is 10000). Values without a suffix are parsed as
+// usual. Values that overflow the field's type are a syntax error.
+// The extended flag enables the extended duration syntax described under
+// Reader.ExtendedDurations for time.Duration values in the field. For durations, the unit flag
+// sets the unit of bare numbers (e.g., unit=s reads 30 as 30s), which may be any unit of
+// time.ParseDuration, d, or w.
+// The fdname flag names a socket passed by systemd socket activation (see LISTEN_FDNAMES in
+// sd_listen_fds(3)) to load into the field, as fd://name, when the field has no value. Unlike
+// a default, the socket counts as the field's value.
+// The mode and perm flags set how *os.File and io.Writer fields open files: mode is r (read-only;
+// the default for *os.File), w (write, truncating the file; the default for io.Writer), a (append),
+// or rw (read-write), and perm is the octal permissions of created files (0666 before the umask, by
+// default). A value prefixed with append:
+// is always opened for appending, and stdin, stdout, stderr, and - (stdout) are the standard
+// streams.
+// The min, max, oneof, match, minlen, maxlen, and nonzero flags are constraints, checked against
+// the field's value once loaded (including from its default). A field violating a constraint fails
+// to load with a *KeyError holding its key and a *ConstraintError naming the constraint, which is
+// subject to the quiet flag and Reader.AllErrors like any other error. The min and max flags bound
+// numbers, durations, and times; oneof=a|b|c requires the value to equal one of the options; and
+// match requires strings to match a regular expression (unanchored, so use ^ and $ to match the
+// whole value). Bounds and options are parsed like the field's value, using its other flags (e.g.,
+// min=1MiB with unit=bytes), and these constraints apply to each element of slices and arrays and
+// each value of maps. The minlen and maxlen flags bound the number of characters of strings and
+// the length of slices, arrays, and maps, and nonzero requires a value other than the zero value
+// of the field's type (including empty strings and slices, and nil pointers or pointers to zero
+// values). Constraints are not checked for fields with no value, so use the required flag to
+// require one.
+// The layout flag sets the layout used to parse time.Time values in the field, in place of RFC 3339.
+// It may name a layout constant of the time package (e.g., RFC1123, DateTime, or DateOnly), be
+// unix or unixms for a number of seconds or milliseconds since the Unix epoch, or be a layout for
+// time.Parse (e.g., `envi:",layout=2006-01-02 15:04"`). Times without a zone are parsed as UTC.
+// Commas in flags must be escaped with a backslash, as must a backslash preceding a comma or another
+// backslash. Since struct tags are quoted, the backslash itself is doubled in the tag (e.g.,
+// `envi:",default=a\\,b"` has the default "a,b").
 // Fields with the suffix "-" are not unmarshaled into, and fields with an empty suffix use their
-// field name as the suffix (without any change in case).
+// field name as the suffix, converted by the Reader's Names (without any change in case by
+// default).
 //
 // Slices of slices are supported but will only ever contain slices of single values.
+//
+// Arrays are loaded the same way as slices. Receiving more values than an array's length is an
+// ErrArrayLength error, and elements past the values received are set to their zero values.
+//
+// Maps are loaded by listing the keys of the Reader's Source that begin with the map's key and Sep
+// (e.g., LIMITS_foo and LIMITS_bar for a map at LIMITS), so the Source must be a KeyLister. The
+// remainder of each key is decoded as a map key. If the map's values are structs, maps, slices, or
+// arrays, the map key ends at the next Sep and the rest of the key names the value's fields or
+// elements (e.g., BACKENDS_foo_HOST is field HOST of map key foo, and PORTS_foo_1 is the first
+// element of map key foo). Existing map entries are kept and only
+// overwritten by those keys that have values.
 func (r *Reader) Load(dst interface{}, val, key string) (err error) {
 	defer swallowLoadPanic("Load", key, &err)
-	return r.readState().load(dst, val, key)
+	rs := r.readState()
+	return rs.loadDst(dst, key, func(dst interface{}) error { return rs.load(dst, val, key) })
 }
 
 func (r *Reader) readState() readState {
-	return (readState{Reader: r}).reset()
+	return (readState{Reader: r, errs: new(Errors), closers: new([]io.Closer)}).reset()
 }
 
 // readState is the state of a Reader.
 type readState struct {
 	*Reader
 	depth int
+	// literal is true when loading values that didn't come from the Reader's source (e.g., field
+	// defaults), and so must be split with Split even if the source is a Multienv.
+	literal bool
+	// defaulted is set to true when a default is assigned to the struct field being loaded or any
+	// of its fields.
+	defaulted *bool
+	// path is the Go path of the value being loaded, relative to the destination passed to Getenv
+	// or Load (e.g., "DB.Peers[0].Host").
+	path string
+	// flags are the flags of the struct field being loaded (e.g., file, strict, and unit), which
+	// also apply to its slice elements and map values.
+	flags structFlags
+	// trace records the environment variables looked up, if tracing.
+	trace *Trace
+	// closers holds the files and listeners opened while loading, to close if loading fails. It's
+	// shared by all readStates of a single Getenv or Load.
+	closers *[]io.Closer
+	// errs holds the errors of all fields that failed to load -- only missing required fields are
+	// held unless AllErrors is set. It's shared by all readStates of a single Getenv or Load.
+	errs *Errors
+}
+
+// finish returns the result of loading the value identified by key, where err is the error
+// returned by loading it. If AllErrors is set and any fields failed to load, finish returns them
+// as Errors. Otherwise, if any required fields were missing, it returns a *MissingError in place of
+// err, unless err is some error other than a no-value error.
+func (r readState) finish(key string, err error) error {
+	if err = r.collectErrors(key, err); err != nil && !IsNoValue(err) {
+		r.closeAll()
+	}
+	return err
+}
+
+// collectErrors returns the error of a Getenv or Load call, given the error returned by loading
+// key and the errors of its fields.
+func (r readState) collectErrors(key string, err error) error {
+	errs := *r.errs
+	if len(errs) == 0 {
+		return err
+	} else if r.AllErrors {
+		if err != nil && !IsNoValue(err) {
+			errs = append(errs, &KeyError{Key: key, Field: r.path, Err: err})
+		}
+		return errs
+	} else if err != nil && !IsNoValue(err) {
+		return err
+	}
+
+	keys := make([]string, len(errs))
+	for i, e := range errs {
+		keys[i] = e.Key
+	}
+	return &MissingError{Keys: keys}
+}
+
+// addError records that the field identified by key failed to load with err.
+func (r readState) addError(key string, err error) {
+	ke, ok := err.(*KeyError)
+	if ok && ke.Key == key {
+		copied := *ke
+		ke = &copied
+	} else {
+		ke = &KeyError{Key: key, Err: err}
+	}
+	if ke.Field == "" {
+		ke.Field = r.path
+	}
+	*r.errs = append(*r.errs, ke)
+}
+
+// at returns a readState for loading the value at the Go path elem, relative to the current
+// readState's path. Elements beginning with "[" are indices and not separated by dots.
+func (r readState) at(elem string) readState {
+	if r.path != "" && !strings.HasPrefix(elem, "[") {
+		elem = "." + elem
+	}
+	r.path += elem
+	return r
+}
+
+// loadElem loads the environment variable key into dst as an element of a slice or map, with the
+// readState's depth reset. If the element has no value, any missing required fields under it are
+// discarded, since the element is absent rather than incomplete.
+func (r readState) loadElem(dst interface{}, key string) (err error) {
+	defer swallowLoadPanic("Getenv", key, &err)
+	mark := len(*r.errs)
+	if err = r.reset().loadFromEnv(dst, key); IsNoValue(err) {
+		*r.errs = (*r.errs)[:mark]
+	}
+	return err
 }
 
 func (r readState) loadFromEnv(dst interface{}, key string) error {
-	val, err := r.getenv(key)
+	val, literal, err := r.lookup(dst, key)
+	if literal {
+		r = r.asLiteral()
+	}
+	return r.loadValue(dst, val, key, err)
+}
+
+// lookup retrieves the value of key for loading into dst. If files are enabled for the readState
+// (see Reader.FileSuffix) and dst is not a struct or map, it falls back to reading the file named
+// by key's file variable, in which case literal is true. The lookup is recorded in the readState's
+// trace, if any, before the value is expanded.
+func (r readState) lookup(dst interface{}, key string) (val string, literal bool, err error) {
+	val, err = r.getenv(key)
+	t := reflect.TypeOf(dst)
+	compound := t != nil && t.Kind() == reflect.Ptr && r.isCompoundType(t.Elem())
+
+	tkey, path := key, ""
+	if suffix := r.fileSuffix(); suffix != "" && !compound {
+		if path, val, err = r.getenvFile(key, key+suffix, val, err); path != "" {
+			tkey = key + suffix
+		}
+	}
+	r.traceLookup(tkey, path, val, err, compound)
+	if err == nil && path == "" {
+		val, err = r.expand(key, val)
+	}
+	return val, path != "", err
+}
+
+// fileSuffix returns the suffix of file variables for the readState, or the empty string if files
+// are not enabled.
+func (r readState) fileSuffix() string {
+	if r.FileSuffix != "" {
+		return r.FileSuffix
+	} else if r.flags.file {
+		return DefaultFileSuffix
+	}
+	return ""
+}
+
+// getenvFile returns the contents of the file named by the variable fkey if key, which holds val
+// and err, has no value. If the file was read, path is its path. Both key and fkey having values is
+// an error.
+func (r readState) getenvFile(key, fkey, val string, err error) (path, fval string, ferr error) {
+	path, ferr = r.getenv(fkey)
+	if IsNoValue(ferr) {
+		return "", val, err
+	} else if ferr != nil {
+		return "", "", ferr
+	} else if err == nil {
+		return "", "", newKeyError(key, fmt.Errorf("%w: %s", ErrFileConflict, fkey))
+	} else if !IsNoValue(err) {
+		return "", "", err
+	}
+
+	p, rerr := os.ReadFile(path)
+	if rerr != nil {
+		return "", "", newKeyError(fkey, rerr)
+	}
+	return path, trimNewline(string(p)), nil
+}
+
+// trimNewline removes a single trailing newline (\n or \r\n) from s.
+func trimNewline(s string) string {
+	if !strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return strings.TrimSuffix(s[:len(s)-1], "\r")
+}
+
+// loadValue loads val into dst, where val and err are the result of looking up key.
+func (r readState) loadValue(dst interface{}, val, key string, err error) error {
 	if err != nil && !(IsNoValue(err) && tryNoVal(dst)) {
 		if err == ErrNoValue {
 			err = NoValueError(key)
@@ -140,6 +447,13 @@ func (r readState) reset() readState {
 	return r
 }
 
+// asLiteral returns a readState for loading values that didn't come from the Reader's source. It
+// does not modify the current readState.
+func (r readState) asLiteral() readState {
+	r.literal = true
+	return r
+}
+
 // canParse returns whether or not the current readState can parse a struct.
 func (r readState) canParseStruct() bool {
 	return r.depth > 0
@@ -157,10 +471,52 @@ func (r *Reader) getenv(key string) (v string, err error) {
 	return "", ErrNoValue
 }
 
+// listKeys returns all keys held by the Reader's environment variable source that begin with
+// prefix. If no source is configured, it lists the keys of the process environment. If the source
+// is not a KeyLister, it returns ErrKeysUnavailable.
+func (r *Reader) listKeys(prefix string) ([]string, error) {
+	if r == nil || r.Source == nil {
+		return OSEnv.Keys(prefix)
+	} else if kl, ok := r.Source.(KeyLister); ok {
+		return kl.Keys(prefix)
+	}
+	return nil, ErrKeysUnavailable
+}
+
+// seqLen returns the number of consecutive key_N indices, counting up from 1, held by the Reader's
+// source. Keys are counted if they begin with an index (e.g., key_2_Field counts for index 2). If
+// the source cannot list its keys, ok is false.
+func (r readState) seqLen(key string) (n int, ok bool) {
+	prefix := key + r.Sep
+	keys, err := r.listKeys(prefix)
+	if err != nil {
+		return 0, false
+	}
+
+	seen := make(map[int]bool, len(keys))
+	for _, k := range keys {
+		rest := k[len(prefix):]
+		end := 0
+		for end < len(rest) && '0' <= rest[end] && rest[end] <= '9' {
+			end++
+		}
+		if i, err := strconv.Atoi(rest[:end]); err == nil {
+			seen[i] = true
+		}
+	}
+
+	for seen[n+1] {
+		n++
+	}
+	return n, true
+}
+
 func (r readState) load(dst interface{}, val, key string) (err error) {
 	var ok bool
-	if ok, err = loadTypeSwitch(dst, val, key); !ok {
-		err = r.loadReflect(dst, val, key)
+	if ok, err = r.loadDecoder(dst, val, key); !ok {
+		if ok, err = r.loadTypeSwitch(dst, val, key); !ok {
+			err = r.loadReflect(dst, val, key)
+		}
 	}
 	if err == ErrNoValue {
 		err = NoValueError(key)
@@ -168,10 +524,36 @@ func (r readState) load(dst interface{}, val, key string) (err error) {
 	return err
 }
 
-func loadTypeSwitch(dst interface{}, val, key string) (ok bool, err error) {
+func (r readState) loadTypeSwitch(dst interface{}, val, key string) (ok bool, err error) {
 	switch out := dst.(type) {
 	case Unmarshaler:
 		err = out.UnmarshalEnv(key, val)
+	case *time.Time:
+		err = loadTime(out, val, r.flags.layout)
+	case *net.Listener:
+		if err = loadListener(out, val); err == nil {
+			r.track(*out)
+		}
+	case **os.File:
+		err = r.loadFile(out, val)
+	case *io.Writer:
+		err = r.loadWriter(out, val)
+	case **time.Location:
+		err = loadLocationIndirect(out, val)
+	case *net.IP:
+		err = loadIP(out, val)
+	case *net.IPNet:
+		err = loadIPNet(out, val)
+	case **net.IPNet:
+		err = loadIPNetIndirect(out, val)
+	case *net.HardwareAddr:
+		err = loadHardwareAddr(out, val)
+	case *netip.Addr:
+		err = loadNetipAddr(out, val)
+	case *netip.Prefix:
+		err = loadNetipPrefix(out, val)
+	case *netip.AddrPort:
+		err = loadNetipAddrPort(out, val)
 	case encoding.TextUnmarshaler:
 		err = loadTextUnmarshaler(out, val)
 	case *string:
@@ -185,7 +567,7 @@ func loadTypeSwitch(dst interface{}, val, key string) (ok bool, err error) {
 	case **url.URL:
 		err = loadURLIndirect(out, val)
 	case *time.Duration:
-		err = loadDuration(out, val)
+		err = r.loadDuration(out, val)
 	default:
 		return false, nil
 	}
@@ -235,33 +617,39 @@ func loadURLIndirect(out **url.URL, val string) error {
 	return err
 }
 
-func loadDuration(out *time.Duration, val string) error {
-	if val == "" {
-		return ErrNoValue
-	}
-	d, err := time.ParseDuration(val)
-	if err == nil {
-		*out = d
-	}
-	return err
-}
-
 func (r readState) loadReflect(dst interface{}, val, key string) error {
 	switch out := indirect(reflect.ValueOf(dst)); out.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return loadInt(out, val)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return loadUint(out, val)
-	case reflect.Float32, reflect.Float64:
-		return loadFloat(out, val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return r.loadNumber(out, val)
 	case reflect.Slice:
 		return r.loadSlice(out, val, key)
+	case reflect.Array:
+		return r.loadArray(out, val, key)
+	case reflect.Map:
+		return r.loadMap(out, key)
 	case reflect.Struct:
 		return r.loadStruct(out, key)
 	}
 	return &TypeError{reflect.TypeOf(dst)}
 }
 
+// loadNumber loads val into the integer or float out, accepting unit suffixes if the field has
+// the unit flag.
+func (r readState) loadNumber(out reflect.Value, val string) error {
+	if r.flags.unit != "" {
+		return loadUnits(out, val, r.flags.unit)
+	}
+	switch out.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return loadInt(out, val)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return loadUint(out, val)
+	}
+	return loadFloat(out, val)
+}
+
 func loadInt(out reflect.Value, val string) error {
 	if val == "" {
 		return ErrNoValue
@@ -321,14 +709,31 @@ func isMarshalerType(t reflect.Type) bool {
 	return t.Implements(unmarshalerType) || t.Implements(textUnmarshalerType)
 }
 
+// scalarTypes are the struct and slice types that are loaded from a single value.
+var scalarTypes = map[reflect.Type]bool{
+	reflect.TypeOf(url.URL{}):             true,
+	reflect.TypeOf(net.IP(nil)):           true,
+	reflect.TypeOf(net.IPNet{}):           true,
+	reflect.TypeOf(net.HardwareAddr(nil)): true,
+	reflect.TypeOf(os.File{}):             true,
+}
+
+// pointerTypes are the pointer types that are assigned whole, instead of allocated and loaded into.
+// Locations are only loaded through pointers, since time.Local can't be copied before it's used.
+var pointerTypes = map[reflect.Type]bool{
+	reflect.TypeOf((*os.File)(nil)):       true,
+	reflect.TypeOf((*time.Location)(nil)): true,
+}
+
 func isSplitType(t reflect.Type) bool {
-	if isMarshalerType(t) {
+	if pointerTypes[t] {
 		return true
 	} else if t.Kind() == reflect.Ptr {
 		t = t.Elem()
-		if isMarshalerType(t) {
-			return true
-		}
+	}
+	// Unmarshalers are usually implemented on pointers, so check both t and *t.
+	if isMarshalerType(t) || isMarshalerType(reflect.PtrTo(t)) || scalarTypes[t] {
+		return true
 	}
 
 	switch t.Kind() {
@@ -341,40 +746,58 @@ func isSplitType(t reflect.Type) bool {
 	return false
 }
 
-func (r *Reader) splitstring(key, value string) []string {
-	if me, ok := r.Source.(Multienv); ok {
-		if all, err := me.GetenvAll(key); err != nil {
+func (r readState) splitstring(key, value string) []string {
+	src := r.Source
+	// Only treat values from a Layered source (e.g., a Chain) as multiple values if the layer they
+	// came from is a Multienv.
+	if l, ok := findLayer(src, key); ok && !r.literal {
+		src = l.Env
+	}
+
+	if me, ok := src.(Multienv); ok && !r.literal {
+		all, err := me.GetenvAll(key)
+		if err == nil && r.Expand {
+			// Copy the values, since they may be held by the source.
+			all = append([]string(nil), all...)
+			for i := 0; err == nil && i < len(all); i++ {
+				all[i], err = r.expand(key, all[i])
+			}
+		}
+		if err != nil {
 			// Will be caught by Load.
 			panic(err)
-		} else {
-			return all
 		}
-	} else if r != nil && r.Split != nil {
+		return all
+	} else if r.Split != nil {
 		return r.Split.SplitString(key, value)
 	}
 	return strings.Fields(value)
 }
 
-func (r readState) loadSliceSeq(out reflect.Value, elemtype reflect.Type, val, key string) (ok bool, err error) {
+func (r readState) loadSliceSeq(out reflect.Value, elemtype reflect.Type, val, key string, maxLen int) (ok bool, err error) {
 	if kind := elemtype.Kind(); val != "" && kind != reflect.Ptr && kind != reflect.Struct {
 		return false, nil
 	}
 
-	var (
-		slice  = reflect.MakeSlice(out.Type(), 0, 1)
-		maxLen = r.MaxSliceLen
-	)
+	slice := reflect.MakeSlice(out.Type(), 0, 1)
 
 	const minSliceLen = 1
 	if maxLen < minSliceLen {
 		maxLen = DefaultMaxSliceLen
 	}
 
+	// Skip probing indices that don't exist if the source can tell us which ones do.
+	if n, ok := r.seqLen(key); ok && n == 0 {
+		return true, NoValueError(key + r.Sep + strconv.Itoa(minSliceLen))
+	} else if ok && n < maxLen {
+		maxLen = n
+	}
+
 	for i := 1; i <= maxLen && err == nil; i++ {
 		elemKey := key + r.Sep + strconv.Itoa(i)
 		tmp := reflect.New(elemtype)
 		dst := allocindirect(indirect(tmp))
-		if err = r.Getenv(dst.Interface(), elemKey); IsNoValue(err) {
+		if err = r.at("["+strconv.Itoa(i-1)+"]").loadElem(dst.Interface(), elemKey); IsNoValue(err) {
 			break
 		}
 		slice = reflect.Append(slice, tmp.Elem())
@@ -399,7 +822,7 @@ func (r readState) loadSliceSplit(out reflect.Value, elemtype reflect.Type, val,
 
 	for i, v := range vals {
 		elemKey := key + r.Sep + strconv.Itoa(1+i)
-		if err = r.Load(slice.Index(i).Addr().Interface(), v, elemKey); err != nil {
+		if err = r.load(slice.Index(i).Addr().Interface(), v, elemKey); err != nil {
 			// Do set it, because there might be open resources held by the slice (files) that
 			// haven't been closed. It's on the person who called Getenv to clean up anything that
 			// needs closing after an error.
@@ -421,9 +844,15 @@ func (r readState) loadSliceSplit(out reflect.Value, elemtype reflect.Type, val,
 }
 
 func (r readState) loadSlice(out reflect.Value, val, key string) error {
+	return r.loadSliceLen(out, val, key, r.MaxSliceLen)
+}
+
+// loadSliceLen loads a slice from either val, split into multiple values, or the keys key_1
+// through key_N, where N is at most maxLen (or DefaultMaxSliceLen, if maxLen is less than 1).
+func (r readState) loadSliceLen(out reflect.Value, val, key string, maxLen int) error {
 	elemtype := out.Type().Elem()
-	ok, err := r.loadSliceSeq(out, elemtype, val, key)
-	if ok && (err == nil || !IsNoValue(err) || !isSplitType(elemtype)) {
+	ok, err := r.loadSliceSeq(out, elemtype, val, key, maxLen)
+	if ok && (err == nil || !IsNoValue(err) || !r.isSplitType(elemtype)) {
 		return err
 	} else if val == "" {
 		return NoValueError(key)
@@ -432,6 +861,139 @@ func (r readState) loadSlice(out reflect.Value, val, key string) error {
 	return r.loadSliceSplit(out, elemtype, val, key)
 }
 
+// loadArray loads an array the same way as a slice. It is an error for the array to receive more
+// elements than its length, or, if the strict flag is set, fewer elements than its length.
+// Elements past those received are set to their zero values.
+func (r readState) loadArray(out reflect.Value, val, key string) error {
+	n := out.Len()
+	slice := reflect.New(reflect.SliceOf(out.Type().Elem())).Elem()
+	// Look for one more element than the array can hold to detect overflow.
+	err := r.loadSliceLen(slice, val, key, n+1)
+	// Errors loading elements are reported in place of the number of elements loaded, since loading
+	// stops at the first invalid element.
+	failed := err != nil && !IsNoValue(err)
+	if got := slice.Len(); !failed && (got > n || (r.flags.strict && got != n && err == nil)) {
+		return newKeyError(key, fmt.Errorf("%w: got %d elements for length %d", ErrArrayLength, got, n))
+	} else if got == 0 {
+		return err
+	}
+
+	// Assign whatever was loaded, as with slices, in case it holds resources that must be closed.
+	for i := 0; i < n; i++ {
+		if i < slice.Len() {
+			out.Index(i).Set(slice.Index(i))
+		} else {
+			out.Index(i).Set(reflect.Zero(out.Type().Elem()))
+		}
+	}
+	return err
+}
+
+// mapNames returns the sorted, unique map key names found in keys following prefix. If compound is
+// true, names end at the first separator following the prefix, since the remainder of the key
+// belongs to the map value (e.g., a struct field).
+func (r readState) mapNames(keys []string, prefix string, compound bool) []string {
+	seen := make(map[string]bool, len(keys))
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		name := k[len(prefix):]
+		if i := strings.Index(name, r.Sep); compound && r.Sep != "" && i >= 0 {
+			name = name[:i]
+		}
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isCompoundType returns whether values of type t are loaded from keys beneath their own key (i.e.,
+// structs and maps) rather than from their own key.
+func (r *Reader) isCompoundType(t reflect.Type) bool {
+	if r.isSplitType(t) {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	kind := t.Kind()
+	return kind == reflect.Struct || kind == reflect.Map
+}
+
+// hasSubkeys returns whether values of type t may be loaded from keys beneath their own key:
+// compound types, and slices and arrays, whose elements may be loaded from numbered keys (e.g.,
+// KEY_1). Byte slices are loaded from a single value.
+func (r *Reader) hasSubkeys(t reflect.Type) bool {
+	if r.isCompoundType(t) {
+		return true
+	} else if r.isSplitType(t) {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	kind := t.Kind()
+	return (kind == reflect.Slice && t.Elem().Kind() != reflect.Uint8) || kind == reflect.Array
+}
+
+func (r readState) loadMap(out reflect.Value, key string) error {
+	prefix := key
+	if prefix != "" {
+		prefix += r.Sep
+	}
+
+	keys, err := r.listKeys(prefix)
+	if err != nil {
+		return newKeyError(key, err)
+	}
+
+	var (
+		typ    = out.Type()
+		names  = r.mapNames(keys, prefix, r.hasSubkeys(typ.Elem()))
+		m      = out
+		loaded = 0
+	)
+
+	if len(names) == 0 {
+		return NoValueError(key)
+	}
+
+	if m.IsNil() {
+		m = reflect.MakeMapWithSize(typ, len(names))
+	}
+
+	for _, name := range names {
+		elemKey := prefix + name
+		mk := reflect.New(typ.Key())
+		if err = r.load(allocindirect(indirect(mk)).Interface(), name, elemKey); err != nil {
+			return err
+		}
+
+		tmp := reflect.New(typ.Elem())
+		// Start from the map's current value for the key, if any, the same as struct fields.
+		if cur := m.MapIndex(mk.Elem()); cur.IsValid() {
+			tmp.Elem().Set(cur)
+		}
+		dst := allocindirect(indirect(tmp))
+		if err = r.at("["+name+"]").loadElem(dst.Interface(), elemKey); IsNoValue(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		m.SetMapIndex(mk.Elem(), tmp.Elem())
+		loaded++
+	}
+
+	if loaded == 0 {
+		return NoValueError(key)
+	}
+	out.Set(m)
+	return nil
+}
+
 func (r readState) loadStruct(out reflect.Value, key string) (err error) {
 	// NOTE: struct loading ignores ErrNoValue
 	typ := out.Type()
@@ -444,11 +1006,13 @@ func (r readState) loadStruct(out reflect.Value, key string) (err error) {
 	// Load non-struct fields
 	for fid, n := 0, typ.NumField(); fid < n; fid++ {
 		ftyp := out.Field(fid).Type()
+		split := r.isSplitType(ftyp)
 		for ftyp.Kind() == reflect.Ptr {
 			ftyp = ftyp.Elem()
 		}
 
-		if ftyp.Kind() == reflect.Struct {
+		// Structs loaded from a single value (e.g., time.Time) are loaded like any other scalar.
+		if ftyp.Kind() == reflect.Struct && !split && !r.isSplitType(ftyp) {
 			nestFields = append(nestFields, fid)
 			continue
 		}
@@ -505,9 +1069,7 @@ func (r readState) loadStructField(out reflect.Value, typ reflect.Type, fieldIdx
 	}
 
 	var (
-		// NOTE: this does not respect escapes or anything -- don't know why you'd
-		// need them but you shouldn't plan on having them
-		etag  = strings.Split(f.Tag.Get("envi"), ",")
+		etag  = splitTag(f.Tag.Get("envi"))
 		fname = etag[0]
 		flags = structFlags{sep: r.Sep}
 	)
@@ -515,37 +1077,119 @@ func (r readState) loadStructField(out reflect.Value, typ reflect.Type, fieldIdx
 	if fname == "-" {
 		return
 	}
-	fname = flags.fieldName(key, etag[0], f.Name)
+	fname = flags.fieldName(key, etag[0], r.mapName(f.Name))
+	r = r.at(f.Name)
+	r.flags = flags
 
 	var (
 		field  = out.FieldByIndex(f.Index)
 		tmp    = reflect.New(field.Type()).Elem()
 		target = allocindirect(indirect(tmp))
 		dst    = target.Interface()
+
+		outer     = r.defaulted
+		defaulted bool
+		violated  bool
 	)
+	r.defaulted = &defaulted
 
 	// Only copy field's value to temporary storage if it's valid
-	if fi := reflect.Indirect(field); fi.IsValid() {
+	if pointerTypes[field.Type()] {
+		target.Elem().Set(field)
+	} else if fi := reflect.Indirect(field); fi.IsValid() {
 		target.Elem().Set(fi)
 	}
-	if err = r.loadFromEnv(dst, fname); err != nil && !IsNoValue(err) && !flags.quiet {
-		return isset, err
+	mark := r.traceLen()
+	err = r.loadField(dst, fname, &flags)
+	if err == nil || (IsNoValue(err) && defaulted) {
+		// Constraint violations are handled like any other error loading the field, except that
+		// quiet fields keep their previous value rather than the one violating the constraint, and
+		// aren't counted as set.
+		if cerr := r.checkConstraints(tmp, fname); cerr != nil {
+			tmp.Set(field)
+			err, violated = cerr, true
+		}
+	}
+	if err != nil && !IsNoValue(err) && !flags.quiet {
+		if !r.AllErrors {
+			return isset, err
+		}
+		// The field had a value, even if it was invalid, so count it as set.
+		r.addError(fname, err)
+		return true, nil
+	} else if IsNoValue(err) && flags.required && !defaulted {
+		r.addError(fname, ErrRequired)
 	}
 	if flags.quiet {
-		if !IsNoValue(err) {
+		if !IsNoValue(err) && !violated {
 			field.Set(tmp)
 			isset = true
 		}
+		if e := r.traceEntry(mark, fname); e != nil && err != nil && !IsNoValue(err) {
+			e.Quiet = true
+		}
 	} else if err == nil {
 		field.Set(tmp)
 		isset = true
 	}
 
+	// Fields that only received defaults aren't counted as set, since they don't indicate that the
+	// struct is present in the environment. Nil pointers are left nil, so that defaults don't
+	// allocate otherwise-absent (and possibly recursive) structs.
+	if !isset && defaulted && !(field.Kind() == reflect.Ptr && field.IsNil()) {
+		field.Set(tmp)
+		if outer != nil {
+			*outer = true
+		}
+	}
+
 	return isset, nil
 }
 
+// mapName returns the environment variable suffix for the struct field name.
+func (r *Reader) mapName(name string) string {
+	if r.Names == nil {
+		return name
+	}
+	return r.Names.MapName(name)
+}
+
+// loadField loads the environment variable key into dst. If key has no value and the field has a
+// default, the default is loaded into dst instead -- the no-value error is still returned in that
+// case, but the readState is marked as defaulted.
+func (r readState) loadField(dst interface{}, key string, flags *structFlags) error {
+	mark := r.traceLen()
+	val, literal, err := r.lookup(dst, key)
+	if literal {
+		r = r.asLiteral()
+	}
+	if err == nil && val == "" && flags.hasDefault && r.DefaultEmpty {
+		err = NoValueError(key)
+	}
+
+	err = r.loadValue(dst, val, key, err)
+	if _, ok := listenFD(flags.fdname); ok && IsNoValue(err) {
+		// Fall back to a socket passed by systemd, which counts as a value, unlike a default.
+		err = r.asLiteral().load(dst, fdScheme+flags.fdname, key)
+	}
+	if !IsNoValue(err) || !flags.hasDefault {
+		return err
+	}
+
+	def, derr := r.expand(key, flags.def)
+	if derr != nil {
+		return derr
+	}
+	if derr := r.asLiteral().load(dst, def, key); derr != nil {
+		return derr
+	}
+	*r.defaulted = true
+	r.traceDefault(mark, key, flags.def)
+	return err
+}
+
 // tryNoVal returns whether a no-value error should be ignored for the given destination interface.
-// This currently only covers structs and values that implement Unmarshaler.
+// This currently only covers structs, slices, maps, and values that implement Unmarshaler.
 //
 // encoding.TextUnmarshaler gets a pass right now.
 func tryNoVal(dst interface{}) bool {
@@ -559,7 +1203,7 @@ func tryNoVal(dst interface{}) bool {
 	}
 
 	kind := v.Type().Elem().Kind()
-	return kind == reflect.Struct || kind == reflect.Slice
+	return kind == reflect.Struct || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
 }
 
 func swallowLoadPanic(fn string, key string, err *error) {
@@ -581,8 +1225,27 @@ func swallowLoadPanic(fn string, key string, err *error) {
 }
 
 type structFlags struct {
-	quiet bool
-	sep   string
+	quiet      bool
+	required   bool
+	sep        string
+	def        string
+	hasDefault bool
+	file       bool
+	strict     bool
+	unit       string
+	layout     string
+	extended   bool
+	fdname     string
+	mode       string
+	perm       string
+	// Constraints hold their whole flag (e.g., "min=1"), for errors, or are empty if not set.
+	min     string
+	max     string
+	oneof   string
+	match   string
+	minlen  string
+	maxlen  string
+	nonzero string
 }
 
 func (flags *structFlags) fieldName(key, tagName, fieldName string) (name string) {
@@ -598,8 +1261,25 @@ func (flags *structFlags) fieldName(key, tagName, fieldName string) (name string
 
 func (flags *structFlags) parse(tags []string) {
 	const (
-		fSep   = "sep="
-		fQuiet = "quiet"
+		fSep      = "sep="
+		fQuiet    = "quiet"
+		fDefault  = "default="
+		fRequired = "required"
+		fFile     = "file"
+		fStrict   = "strict"
+		fUnit     = "unit="
+		fLayout   = "layout="
+		fExtended = "extended"
+		fFDName   = "fdname="
+		fMode     = "mode="
+		fPerm     = "perm="
+		fMin      = "min="
+		fMax      = "max="
+		fOneOf    = "oneof="
+		fMatch    = "match="
+		fMinLen   = "minlen="
+		fMaxLen   = "maxlen="
+		fNonzero  = "nonzero"
 	)
 
 	for _, t := range tags {
@@ -608,6 +1288,60 @@ func (flags *structFlags) parse(tags []string) {
 			flags.sep = t[len(fSep):]
 		case t == fQuiet:
 			flags.quiet = true
+		case strings.HasPrefix(t, fDefault):
+			flags.def, flags.hasDefault = t[len(fDefault):], true
+		case t == fRequired:
+			flags.required = true
+		case t == fFile:
+			flags.file = true
+		case t == fStrict:
+			flags.strict = true
+		case strings.HasPrefix(t, fUnit):
+			flags.unit = t[len(fUnit):]
+		case strings.HasPrefix(t, fLayout):
+			flags.layout = t[len(fLayout):]
+		case t == fExtended:
+			flags.extended = true
+		case strings.HasPrefix(t, fFDName):
+			flags.fdname = t[len(fFDName):]
+		case strings.HasPrefix(t, fMode):
+			flags.mode = t[len(fMode):]
+		case strings.HasPrefix(t, fPerm):
+			flags.perm = t[len(fPerm):]
+		case strings.HasPrefix(t, fMin):
+			flags.min = t
+		case strings.HasPrefix(t, fMax):
+			flags.max = t
+		case strings.HasPrefix(t, fOneOf):
+			flags.oneof = t
+		case strings.HasPrefix(t, fMatch):
+			flags.match = t
+		case strings.HasPrefix(t, fMinLen):
+			flags.minlen = t
+		case strings.HasPrefix(t, fMaxLen):
+			flags.maxlen = t
+		case t == fNonzero:
+			flags.nonzero = t
+		}
+	}
+}
+
+// splitTag splits an envi field tag on commas. A comma may be escaped with a backslash to include
+// it in a flag, and a backslash may be escaped with another backslash. All other backslashes are
+// kept as-is.
+func splitTag(tag string) (parts []string) {
+	var b strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch c := tag[i]; {
+		case c == '\\' && i+1 < len(tag) && (tag[i+1] == ',' || tag[i+1] == '\\'):
+			i++
+			b.WriteByte(tag[i])
+		case c == ',':
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
 		}
 	}
+	return append(parts, b.String())
 }
@@ -0,0 +1,86 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecodeFunc decodes the environment variable text val, the value of key, and returns the
+// result. The result must be assignable to the type the DecodeFunc is registered for in
+// Reader.Decoders. A nil result assigns the zero value of the type.
+type DecodeFunc func(key, val string) (interface{}, error)
+
+// Register registers fn as the decoder for values of type T in r.Decoders, allocating
+// r.Decoders if needed. It is not safe to call Register while r is in use.
+func Register[T any](r *Reader, fn func(key, val string) (T, error)) {
+	if r.Decoders == nil {
+		r.Decoders = make(map[reflect.Type]DecodeFunc)
+	}
+	r.Decoders[reflect.TypeOf((*T)(nil)).Elem()] = func(key, val string) (interface{}, error) {
+		return fn(key, val)
+	}
+}
+
+// decoder returns the DecodeFunc registered for t, following pointers, and the number of
+// pointers followed to reach it. If there is none, it returns nil.
+func (r *Reader) decoder(t reflect.Type) (fn DecodeFunc, depth int) {
+	if r == nil || len(r.Decoders) == 0 {
+		return nil, 0
+	}
+	for {
+		if fn, ok := r.Decoders[t]; ok {
+			return fn, depth
+		} else if t.Kind() != reflect.Ptr {
+			return nil, 0
+		}
+		t, depth = t.Elem(), depth+1
+	}
+}
+
+// isSplitType is the same as the isSplitType function, but also includes types with a registered
+// decoder.
+func (r *Reader) isSplitType(t reflect.Type) bool {
+	if fn, _ := r.decoder(t); fn != nil {
+		return true
+	}
+	return isSplitType(t)
+}
+
+// loadDecoder loads val into dst using the DecodeFunc registered for the type dst points to, if
+// any, allocating pointers as needed. If there is no registered decoder, ok is false. Empty values
+// are treated as having no value, as with encoding.TextUnmarshaler.
+func (r readState) loadDecoder(dst interface{}, val, key string) (ok bool, err error) {
+	out := reflect.ValueOf(dst)
+	if out.Kind() != reflect.Ptr || out.IsNil() {
+		return false, nil
+	}
+	fn, depth := r.decoder(out.Type().Elem())
+	if fn == nil {
+		return false, nil
+	} else if val == "" {
+		return true, ErrNoValue
+	}
+
+	x, err := fn(key, val)
+	if err != nil {
+		return true, err
+	}
+
+	out = out.Elem()
+	for ; depth > 0; depth-- {
+		out = allocindirect(out).Elem()
+	}
+	if x == nil {
+		out.Set(reflect.Zero(out.Type()))
+		return true, nil
+	}
+	xv := reflect.ValueOf(x)
+	if !xv.Type().AssignableTo(out.Type()) {
+		return true, fmt.Errorf("decoder for %v returned %T", out.Type(), x)
+	}
+	out.Set(xv)
+	return true, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// vendorLevel stands in for a type from another package that envi can't be taught to decode by
+// adding methods.
+type vendorLevel struct{ name string }
+
+func decodeVendorLevel(key, val string) (vendorLevel, error) {
+	switch val = strings.ToLower(val); val {
+	case "low", "high":
+		return vendorLevel{val}, nil
+	}
+	return vendorLevel{}, mksyntaxerr(val, errors.New("unknown level"))
+}
+
+func TestRegister(t *testing.T) {
+	type config struct {
+		Level   vendorLevel
+		Ptr     *vendorLevel
+		Levels  []vendorLevel
+		Indexed []*vendorLevel
+		ByLevel map[vendorLevel]vendorLevel
+	}
+
+	r := &Reader{Source: Values{
+		"APP_Level":        {"LOW"},
+		"APP_Ptr":          {"high"},
+		"APP_Levels":       {"low", "high"},
+		"APP_Indexed_1":    {"high"},
+		"APP_Indexed_2":    {"low"},
+		"APP_ByLevel_low":  {"high"},
+		"APP_ByLevel_high": {"low"},
+	}, Sep: "_"}
+	Register(r, decodeVendorLevel)
+
+	var got config
+	if err := r.Getenv(&got, "APP"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+
+	low, high := vendorLevel{"low"}, vendorLevel{"high"}
+	want := config{
+		Level:   low,
+		Ptr:     &high,
+		Levels:  []vendorLevel{low, high},
+		Indexed: []*vendorLevel{&high, &low},
+		ByLevel: map[vendorLevel]vendorLevel{low: high, high: low},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Getenv() = %+v; want %+v", got, want)
+	}
+}
+
+func TestDecodersPrecedence(t *testing.T) {
+	// Decoders are consulted before built-in types.
+	r := &Reader{
+		Source: Values{"N": {"ten"}, "EMPTY": {""}},
+		Decoders: map[reflect.Type]DecodeFunc{
+			reflect.TypeOf(0): func(key, val string) (interface{}, error) {
+				if val == "ten" {
+					return 10, nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	if n, err := Get[int](r, "N"); err != nil || n != 10 {
+		t.Errorf("Get() = %d, %v; want 10, <nil>", n, err)
+	}
+	if _, err := Get[int](r, "EMPTY"); !IsNoValue(err) {
+		t.Errorf("Get() error = %v; want no value", err)
+	}
+}
+
+func TestDecodersWrongType(t *testing.T) {
+	r := &Reader{
+		Source: Values{"N": {"1"}},
+		Decoders: map[reflect.Type]DecodeFunc{
+			reflect.TypeOf(0): func(key, val string) (interface{}, error) { return "1", nil },
+		},
+	}
+	if _, err := Get[int](r, "N"); err == nil {
+		t.Fatal("Get() error = <nil>; want error")
+	}
+}
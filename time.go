@@ -0,0 +1,101 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"strconv"
+	"time"
+)
+
+// Epoch layouts for the layout flag.
+const (
+	layoutUnix   = "unix"
+	layoutUnixMs = "unixms"
+)
+
+// timeLayouts are the named layouts accepted by the layout flag, in addition to the epoch layouts.
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+// timeLayout returns the layout named by layout, or layout itself if it isn't a named layout.
+func timeLayout(layout string) string {
+	if l, ok := timeLayouts[layout]; ok {
+		return l
+	}
+	return layout
+}
+
+// loadTime loads val into out using layout, which may be a named layout, an epoch layout, or
+// a time.Parse layout. If layout is empty, val must be in RFC 3339 format.
+func loadTime(out *time.Time, val, layout string) error {
+	if val == "" {
+		return ErrNoValue
+	}
+
+	switch layout {
+	case "":
+		return out.UnmarshalText([]byte(val))
+	case layoutUnix, layoutUnixMs:
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return mksyntaxerr(val, err)
+		}
+		if layout == layoutUnix {
+			*out = time.Unix(n, 0)
+		} else {
+			*out = time.UnixMilli(n)
+		}
+		return nil
+	}
+
+	t, err := time.Parse(timeLayout(layout), val)
+	if err != nil {
+		return mksyntaxerr(val, err)
+	}
+	*out = t
+	return nil
+}
+
+// formatTime formats t using layout, as accepted by loadTime. The layout must not be empty.
+func formatTime(t time.Time, layout string) string {
+	switch layout {
+	case layoutUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case layoutUnixMs:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+	return t.Format(timeLayout(layout))
+}
+
+// loadLocationIndirect loads the time zone named by val (e.g., UTC, Local, or America/New_York)
+// into out.
+func loadLocationIndirect(out **time.Location, val string) error {
+	if val == "" {
+		return ErrNoValue
+	}
+	loc, err := time.LoadLocation(val)
+	if err != nil {
+		return mksyntaxerr(val, err)
+	}
+	*out = loc
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetenvTimeLayouts(t *testing.T) {
+	type config struct {
+		Default time.Time
+		RFC1123 time.Time  `envi:",layout=RFC1123"`
+		Date    time.Time  `envi:",layout=DateOnly"`
+		Custom  *time.Time `envi:",layout=02 Jan 2006 15:04"`
+		Unix    time.Time  `envi:",layout=unix"`
+		UnixMs  time.Time  `envi:",layout=unixms"`
+		Zone    *time.Location
+		Local   *time.Location
+	}
+
+	r := Reader{Source: Values{
+		"test_Default": {"2024-06-01T02:00:00Z"},
+		"test_RFC1123": {"Sat, 01 Jun 2024 02:00:00 UTC"},
+		"test_Date":    {"2024-06-01"},
+		"test_Custom":  {"01 Jun 2024 02:00"},
+		"test_Unix":    {"1717207200"},
+		"test_UnixMs":  {"1717207200500"},
+		"test_Zone":    {"UTC"},
+		"test_Local":   {"Local"},
+	}, Sep: "_"}
+
+	var got config
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+
+	want := time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC)
+	for name, got := range map[string]time.Time{
+		"Default": got.Default,
+		"RFC1123": got.RFC1123,
+		"Date":    got.Date.Add(2 * time.Hour),
+		"Custom":  *got.Custom,
+		"Unix":    got.Unix,
+		"UnixMs":  got.UnixMs.Add(-500 * time.Millisecond),
+	} {
+		if !got.Equal(want) {
+			t.Errorf("Getenv() %s = %v; want %v", name, got, want)
+		}
+	}
+	if got.Zone.String() != "UTC" || got.Local != time.Local {
+		t.Errorf("Getenv() Zone, Local = %v, %v; want UTC, Local", got.Zone, got.Local)
+	}
+
+	// Round trip
+	vals, err := r.Marshal(got, "test")
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, key := range []string{"test_Date", "test_Custom", "test_Unix", "test_UnixMs", "test_Zone", "test_Local"} {
+		if vals[key][0] != r.Source.(Values)[key][0] {
+			t.Errorf("Marshal() %s = %q; want %q", key, vals[key], r.Source.(Values)[key])
+		}
+	}
+}
+
+func TestMarshalTimeLayoutElems(t *testing.T) {
+	type config struct {
+		Dates []time.Time          `envi:",layout=DateOnly"`
+		Pair  [2]time.Time         `envi:",layout=unix"`
+		Named map[string]time.Time `envi:",layout=DateOnly"`
+		Keyed map[time.Time]int    `envi:",layout=DateOnly"`
+	}
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	src := config{
+		Dates: []time.Time{day, day.AddDate(0, 0, 1)},
+		Pair:  [2]time.Time{day, day.Add(time.Hour)},
+		Named: map[string]time.Time{"a": day},
+		Keyed: map[time.Time]int{day: 1},
+	}
+
+	r := Reader{Sep: "_"}
+	vals, err := r.Marshal(src, "test")
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := Values{
+		"test_Dates_1":          {"2024-06-01"},
+		"test_Dates_2":          {"2024-06-02"},
+		"test_Pair_1":           {"1717200000"},
+		"test_Pair_2":           {"1717203600"},
+		"test_Named_a":          {"2024-06-01"},
+		"test_Keyed_2024-06-01": {"1"},
+	}
+	if !reflect.DeepEqual(vals, want) {
+		t.Fatalf("Marshal() = %q; want %q", vals, want)
+	}
+
+	var got config
+	r.Source = vals
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+	for i := range got.Pair {
+		got.Pair[i] = got.Pair[i].UTC()
+	}
+	if !reflect.DeepEqual(got, src) {
+		t.Errorf("Getenv() = %#v; want %#v", got, src)
+	}
+}
+
+func TestGetenvTimeInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		dst  interface{}
+		in   string
+	}{
+		{"Layout", new(struct {
+			T time.Time `envi:",layout=DateOnly"`
+		}), "06/01/2024"},
+		{"Unix", new(struct {
+			T time.Time `envi:",layout=unix"`
+		}), "1.5"},
+		{"Location", new(struct{ T *time.Location }), "Mars/Olympus_Mons"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			r := Reader{Source: Values{"T": {c.in}}}
+			var se *SyntaxError
+			if err := r.Getenv(c.dst, ""); !errors.As(err, &se) || se.Str != c.in {
+				t.Fatalf("Getenv() error = %v; want *SyntaxError for %q", err, c.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// TraceEntry describes a single environment variable looked up by a Reader.
+type TraceEntry struct {
+	Field     string // The Go path of the value being loaded (e.g., "DB.Peers[0].Host")
+	Key       string // The environment variable looked up
+	Source    string // The name of the source that held Key, if Found
+	Value     string // The raw value of Key, or the default text if Defaulted
+	File      string // The path of the file that Value was read from, if Key names a file
+	Found     bool   // Whether Key was found in the source
+	Defaulted bool   // Whether the field was assigned its default
+	Quiet     bool   // Whether the value failed to load and was ignored by the quiet flag
+}
+
+// Trace is a list of the environment variables looked up by a Reader while loading a value, in the
+// order they were looked up. Lookups of struct and map keys without values are omitted, since
+// structs and maps are loaded from the keys beneath them. Its String method formats it as a table
+// suitable for logging, which holds defaults and the paths of files but, since they may be secrets,
+// not the values of environment variables.
+type Trace []TraceEntry
+
+func (t Trace) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, e := range t {
+		field, source, value := e.Field, e.Source, "(unset)"
+		if field == "" {
+			field = "-"
+		}
+		if source == "" {
+			source = "-"
+		}
+		switch {
+		case e.File != "":
+			value = "file " + strconv.Quote(e.File)
+		case e.Defaulted:
+			value = strconv.Quote(e.Value)
+		case e.Found:
+			value = "(set)"
+		}
+		if e.Defaulted {
+			value += " (default)"
+		}
+		if e.Quiet {
+			value += " (quiet)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", field, e.Key, source, value)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// GetenvTrace is the same as Getenv, but also returns a Trace of the environment variables looked
+// up while loading dst, including generated slice keys (e.g., key_1), which source each was found
+// in, and whether a field was defaulted or failed quietly. The Trace is returned even if an error
+// occurs.
+//
+// Source names are the names of the layers of Layered sources, such as a Chain, the result of a
+// String method if the source has one, or the source's type otherwise.
+func (r *Reader) GetenvTrace(dst interface{}, key string) (trace Trace, err error) {
+	defer swallowLoadPanic("Getenv", key, &err)
+	rs := r.readState()
+	rs.trace = &trace
+	err = rs.loadDst(dst, key, func(dst interface{}) error { return rs.loadFromEnv(dst, key) })
+	return trace, err
+}
+
+// traceLookup records the lookup of key, holding val, in the readState's trace, if any. If the
+// value was read from a file, file is its path. Lookups of compound values without a value are not
+// recorded.
+func (r readState) traceLookup(key, file, val string, err error, compound bool) {
+	found := err == nil
+	if r.trace == nil || (!found && compound) {
+		return
+	}
+
+	e := TraceEntry{Field: r.path, Key: key, Value: val, File: file, Found: found}
+	if found {
+		e.Source = r.sourceName(key)
+	}
+	*r.trace = append(*r.trace, e)
+}
+
+// traceLen returns the length of the readState's trace, or 0 if not tracing.
+func (r readState) traceLen() int {
+	if r.trace == nil {
+		return 0
+	}
+	return len(*r.trace)
+}
+
+// traceEntry returns the last entry of the readState's trace for key, recorded at or after index
+// from. If there is no such entry, it returns nil.
+func (r readState) traceEntry(from int, key string) *TraceEntry {
+	if r.trace == nil {
+		return nil
+	}
+	for i := len(*r.trace) - 1; i >= from; i-- {
+		if e := &(*r.trace)[i]; e.Key == key && e.Field == r.path {
+			return e
+		}
+	}
+	return nil
+}
+
+// traceDefault records that the field identified by key was assigned its default, def.
+func (r readState) traceDefault(from int, key, def string) {
+	if r.trace == nil {
+		return
+	}
+	e := r.traceEntry(from, key)
+	if e == nil {
+		*r.trace = append(*r.trace, TraceEntry{Field: r.path, Key: key})
+		e = &(*r.trace)[len(*r.trace)-1]
+	}
+	e.Value, e.Defaulted = def, true
+}
+
+// sourceName returns the name of the source of key for use in a Trace.
+func (r *Reader) sourceName(key string) string {
+	if l, ok := findLayer(r.Source, key); ok {
+		return l.Name
+	}
+	switch src := r.Source.(type) {
+	case nil:
+		return OSEnv.String()
+	case fmt.Stringer:
+		return src.String()
+	}
+	return fmt.Sprintf("%T", r.Source)
+}
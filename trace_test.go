@@ -0,0 +1,97 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetenvTrace(t *testing.T) {
+	type peer struct {
+		Host string
+		Port int `envi:",default=80"`
+	}
+	type config struct {
+		Name    string
+		Level   int `envi:",quiet"`
+		Missing string
+		Peers   []peer
+	}
+
+	r := Reader{
+		Source: Chain{
+			{Name: "flags", Env: Values{"APP_Name": {"svc"}}},
+			{Name: "env", Env: Values{
+				"APP_Level":        {"loud"},
+				"APP_Peers_1_Host": {"a"},
+				"APP_Peers_2_Port": {"81"},
+			}},
+		},
+		Sep:         "_",
+		MaxSliceLen: 2,
+	}
+
+	var dst config
+	trace, err := r.GetenvTrace(&dst, "APP")
+	if err != nil {
+		t.Fatalf("GetenvTrace() error = %v", err)
+	}
+
+	want := Trace{
+		{Field: "Name", Key: "APP_Name", Source: "flags", Value: "svc", Found: true},
+		{Field: "Level", Key: "APP_Level", Source: "env", Value: "loud", Found: true, Quiet: true},
+		{Field: "Missing", Key: "APP_Missing"},
+		{Field: "Peers", Key: "APP_Peers"},
+		{Field: "Peers[0].Host", Key: "APP_Peers_1_Host", Source: "env", Value: "a", Found: true},
+		{Field: "Peers[0].Port", Key: "APP_Peers_1_Port", Value: "80", Defaulted: true},
+		{Field: "Peers[1].Host", Key: "APP_Peers_2_Host"},
+		{Field: "Peers[1].Port", Key: "APP_Peers_2_Port", Source: "env", Value: "81", Found: true},
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("GetenvTrace() trace =\n%v\nwant\n%v", trace, want)
+	}
+
+	str := trace.String()
+	for _, line := range []string{
+		`Level          APP_Level         env    (set) (quiet)`,
+		`Missing        APP_Missing       -      (unset)`,
+		`Peers[0].Port  APP_Peers_1_Port  -      "80" (default)`,
+	} {
+		if !strings.Contains(str, line) {
+			t.Errorf("Trace.String() =\n%s\nmissing line %q", str, line)
+		}
+	}
+	if strings.Contains(str, "loud") {
+		t.Errorf("Trace.String() =\n%s\nholds the value of APP_Level", str)
+	}
+}
+
+func TestGetenvTraceSourceName(t *testing.T) {
+	cases := []struct {
+		name string
+		src  Env
+		want string
+	}{
+		{"Nil", nil, "os"},
+		{"OSEnv", OSEnv, "os"},
+		{"Values", Values{"KEY": {"1"}}, "envi.Values"},
+		{"ChainLayer", Chain{{Name: "file", Env: Values{"KEY": {"1"}}}}, "file"},
+		{"ChainPointer", &Chain{{Name: "file", Env: Values{"KEY": {"1"}}}}, "file"},
+		{"NestedChain", Chain{
+			{Name: "empty", Env: Values{}},
+			{Name: "config", Env: Chain{{Name: "local", Env: Values{"KEY": {"1"}}}}},
+		}, "config/local"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			r := Reader{Source: c.src}
+			if got := r.sourceName("KEY"); got != c.want {
+				t.Fatalf("sourceName() = %q; want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	unitBytes = "bytes"
+	unitSI    = "si"
+)
+
+type unitSuffix struct {
+	suffix string
+	scale  uint64
+}
+
+// binaryUnits are the IEC byte size suffixes, from largest to smallest.
+var binaryUnits = []unitSuffix{
+	{"EiB", 1 << 60},
+	{"PiB", 1 << 50},
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+}
+
+// byteUnits are the suffixes accepted by unit=bytes. They're matched without regard to case, so
+// longer suffixes must precede their own suffixes (e.g., KiB before B).
+var byteUnits = append(binaryUnits[:len(binaryUnits):len(binaryUnits)],
+	unitSuffix{"EB", 1e18},
+	unitSuffix{"PB", 1e15},
+	unitSuffix{"TB", 1e12},
+	unitSuffix{"GB", 1e9},
+	unitSuffix{"MB", 1e6},
+	unitSuffix{"KB", 1e3},
+	unitSuffix{"B", 1},
+)
+
+// siUnits are the suffixes accepted by unit=si. They're case-sensitive, except for k.
+var siUnits = []unitSuffix{
+	{"E", 1e18},
+	{"P", 1e15},
+	{"T", 1e12},
+	{"G", 1e9},
+	{"M", 1e6},
+	{"k", 1e3},
+	{"K", 1e3},
+}
+
+var (
+	errUnitSyntax   = errors.New("invalid number")
+	errUnitFraction = errors.New("value is not a whole number")
+)
+
+// cutUnit returns val without its unit suffix, if it has one of units, and the suffix's scale. If
+// val has no suffix, scale is 0. A suffix is only cut if it follows a decimal number, so that it
+// isn't cut from hexadecimal numbers or exponents (e.g., the B of 0x1B or the E of 1e1E).
+func cutUnit(val, unit string) (num string, scale uint64, err error) {
+	var units []unitSuffix
+	switch unit {
+	case unitBytes:
+		units = byteUnits
+	case unitSI:
+		units = siUnits
+	default:
+		return "", 0, fmt.Errorf("unknown unit %q", unit)
+	}
+
+	for _, u := range units {
+		if len(val) < len(u.suffix) {
+			continue
+		}
+		num, suffix := val[:len(val)-len(u.suffix)], val[len(val)-len(u.suffix):]
+		if suffix != u.suffix && !(unit == unitBytes && strings.EqualFold(suffix, u.suffix)) {
+			continue
+		}
+		if num = strings.TrimSpace(num); isDecimal(num) {
+			return num, u.scale, nil
+		}
+	}
+	return val, 0, nil
+}
+
+// isDecimal returns whether s is a decimal number, with an optional sign and fraction.
+func isDecimal(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	digits, dot := 0, false
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+// loadUnits loads val, which may have a suffix of the given unit (unitBytes or unitSI), into the
+// integer or float out. Values are scaled exactly, so fractional values (e.g., 1.5GiB) are only
+// an error if they aren't whole numbers when loaded into integers.
+func loadUnits(out reflect.Value, val, unit string) error {
+	if val == "" {
+		return ErrNoValue
+	}
+	num, scale, err := cutUnit(val, unit)
+	if err != nil {
+		return err
+	}
+
+	if scale == 0 {
+		switch out.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return loadInt(out, val)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return loadUint(out, val)
+		}
+		return loadFloat(out, val)
+	}
+
+	n, ok := new(big.Rat).SetString(num)
+	if !ok || num == "" || strings.ContainsRune(num, '/') {
+		return mksyntaxerr(val, errUnitSyntax)
+	}
+	n.Mul(n, new(big.Rat).SetInt(new(big.Int).SetUint64(scale)))
+
+	bits := uint(out.Type().Bits())
+	switch out.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !n.IsInt() {
+			return mksyntaxerr(val, errUnitFraction)
+		}
+		i := n.Num()
+		max := new(big.Int).Lsh(big.NewInt(1), bits-1)
+		min := new(big.Int).Neg(max)
+		if i.Cmp(min) < 0 || i.Cmp(max) >= 0 {
+			return mksyntaxerr(val, strconv.ErrRange)
+		}
+		out.SetInt(i.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !n.IsInt() {
+			return mksyntaxerr(val, errUnitFraction)
+		}
+		i := n.Num()
+		if i.Sign() < 0 || uint(i.BitLen()) > bits {
+			return mksyntaxerr(val, strconv.ErrRange)
+		}
+		out.SetUint(i.Uint64())
+	default:
+		f, _ := n.Float64()
+		if math.IsInf(f, 0) || (bits == 32 && math.Abs(f) > math.MaxFloat32) {
+			return mksyntaxerr(val, strconv.ErrRange)
+		}
+		out.SetFloat(f)
+	}
+	return nil
+}
+
+// ByteSize is a number of bytes that is loaded from a size with an optional unit suffix. The
+// suffixes KB, MB, GB, TB, PB, and EB are powers of 1000, while KiB, MiB, GiB, TiB, PiB, and
+// EiB are powers of 1024. The suffix B is a single byte, and suffixes are matched without regard
+// to case (e.g., 512mib is 512MiB). Sizes may be fractional, provided they're a whole number of
+// bytes (e.g., 1.5KiB is 1536 bytes).
+//
+// Integer struct fields with the unit=bytes flag (see Reader.Load) are loaded the same way.
+type ByteSize uint64
+
+// String returns b using the largest binary unit that represents it exactly (e.g., 512MiB), or as
+// a number of bytes (e.g., 1500B).
+func (b ByteSize) String() string {
+	for _, u := range binaryUnits {
+		if uint64(b) >= u.scale && uint64(b)%u.scale == 0 {
+			return strconv.FormatUint(uint64(b)/u.scale, 10) + u.suffix
+		}
+	}
+	return strconv.FormatUint(uint64(b), 10) + "B"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	return loadUnits(reflect.ValueOf(b).Elem(), string(text), unitBytes)
+}
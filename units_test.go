@@ -0,0 +1,127 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestByteSize(t *testing.T) {
+	cases := []struct {
+		in   string
+		want ByteSize
+		bad  bool
+	}{
+		{in: "512", want: 512},
+		{in: "0x10", want: 16},
+		{in: "0x1B", want: 27},
+		{in: "512B", want: 512},
+		{in: "1KB", want: 1000},
+		{in: "1KiB", want: 1024},
+		{in: "512MiB", want: 512 << 20},
+		{in: "512 mib", want: 512 << 20},
+		{in: "1.5GiB", want: 3 << 29},
+		{in: "2GB", want: 2e9},
+		{in: "1TB", want: 1e12},
+		{in: "16EiB", bad: true},
+		{in: "0.5B", bad: true},
+		{in: "-1KB", bad: true},
+		{in: "KB", bad: true},
+		{in: "1/2KB", bad: true},
+		{in: "1XB", bad: true},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.in, func(t *testing.T) {
+			var got ByteSize
+			err := got.UnmarshalText([]byte(c.in))
+			if c.bad {
+				var se *SyntaxError
+				if !errors.As(err, &se) {
+					t.Fatalf("UnmarshalText() error = %v; want *SyntaxError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalText() error = %v", err)
+			} else if got != c.want {
+				t.Fatalf("UnmarshalText() = %d; want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	for size, want := range map[ByteSize]string{
+		0:         "0B",
+		1500:      "1500B",
+		1024:      "1KiB",
+		512 << 20: "512MiB",
+		3 << 29:   "1536MiB",
+		1 << 60:   "1EiB",
+	} {
+		if got := size.String(); got != want {
+			t.Errorf("ByteSize(%d).String() = %q; want %q", uint64(size), got, want)
+		}
+	}
+}
+
+func TestGetenvUnits(t *testing.T) {
+	type config struct {
+		Cache   int64    `envi:",unit=bytes"`
+		Buffer  uint16   `envi:",unit=bytes"`
+		Batch   int      `envi:",unit=si"`
+		Rate    float64  `envi:",unit=si"`
+		Batches []uint32 `envi:",unit=si"`
+		Size    ByteSize
+	}
+
+	r := Reader{Source: Values{
+		"test_Cache":   {"512MiB"},
+		"test_Buffer":  {"64KB"},
+		"test_Batch":   {"10k"},
+		"test_Rate":    {"1.5M"},
+		"test_Batches": {"1k", "2G", "300", "0x1E"},
+		"test_Size":    {"1GiB"},
+	}, Sep: "_"}
+
+	var got config
+	if err := r.Getenv(&got, "test"); err != nil {
+		t.Fatalf("Getenv() error = %v", err)
+	}
+	want := config{
+		Cache:   512 << 20,
+		Buffer:  64000,
+		Batch:   10000,
+		Rate:    1.5e6,
+		Batches: []uint32{1000, 2e9, 300, 30},
+		Size:    1 << 30,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Getenv() = %+v; want %+v", got, want)
+	}
+
+	for name, in := range map[string]Values{
+		"Overflow":    {"test_Buffer": {"64KiB"}},
+		"SliceRange":  {"test_Batches": {"5G"}},
+		"BytesSuffix": {"test_Batch": {"1KB"}},
+		"Exponent":    {"test_Rate": {"1e1E"}},
+	} {
+		r.Source = in
+		if err := r.Getenv(new(config), "test"); !errors.Is(err, strconv.ErrRange) && !errors.Is(err, strconv.ErrSyntax) {
+			t.Errorf("%s: Getenv() error = %v; want range or syntax error", name, err)
+		}
+	}
+
+	r.Source = Values{"V": {"1k"}}
+	var bad struct {
+		V int `envi:",unit=furlongs"`
+	}
+	if err := r.Getenv(&bad, ""); err == nil {
+		t.Errorf("Getenv() with unknown unit error = <nil>; want error")
+	}
+}
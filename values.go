@@ -3,12 +3,20 @@
 
 package envi
 
+import (
+	"sort"
+	"strings"
+)
+
 // Values is an Env- and Multienv-conformant map of keys to strings. Getenv will only return the first value held by the
 // key's slice. If the slice is empty but the key is set, it returns the empty string. GetenvAll will return nil and
 // ErrNoValue only if the key is unset.
 type Values map[string][]string
 
-var _ = Multienv(Values(nil))
+var (
+	_ Multienv  = Values(nil)
+	_ KeyLister = Values(nil)
+)
 
 // Add appends the value to the slice of values held by key.
 func (v Values) Add(key, value string) { v[key] = append(v[key], value) }
@@ -49,3 +57,40 @@ func (v Values) GetenvAll(key string) ([]string, error) {
 	}
 	return nil, NoValueError(key)
 }
+
+// Keys returns all keys held by the receiver that begin with prefix, including keys with no values.
+func (v Values) Keys(prefix string) ([]string, error) {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// Environ returns the receiver's keys and values in the form of os.Environ ("key=value"), sorted by
+// key, for use as a process's environment or in a deployment file. Keys with no values are given
+// empty values. Since each key of an environment holds one value, Environ returns a *KeyError
+// holding ErrMultipleValues if a key holds more than one.
+func (v Values) Environ() ([]string, error) {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	environ := make([]string, 0, len(keys))
+	for _, k := range keys {
+		val := ""
+		switch vals := v[k]; len(vals) {
+		case 0:
+		case 1:
+			val = vals[0]
+		default:
+			return nil, newKeyError(k, ErrMultipleValues)
+		}
+		environ = append(environ, k+"="+val)
+	}
+	return environ, nil
+}
@@ -4,7 +4,9 @@
 package envi
 
 import (
+	"errors"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -70,3 +72,36 @@ func TestValuesMethods(t *testing.T) {
 		t.Errorf("values.GetenvAll(%q) = %#v, %#v; want %#v, %#v", "a", vs, err, nil, wanterr)
 	}
 }
+
+func TestValuesKeys(t *testing.T) {
+	values := Values{
+		"a_1": {"x"},
+		"a_2": {},
+		"b_1": {"y"},
+	}
+
+	keys, err := values.Keys("a_")
+	if err != nil {
+		t.Fatalf("values.Keys(%q) err = %v; want nil", "a_", err)
+	}
+	sort.Strings(keys)
+
+	if want := []string{"a_1", "a_2"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("values.Keys(%q) = %q; want %q", "a_", keys, want)
+	}
+
+	if keys, _ := values.Keys(""); len(keys) != len(values) {
+		t.Errorf("values.Keys(%q) = %q; want all keys", "", keys)
+	}
+}
+
+func TestValuesEnviron(t *testing.T) {
+	got, err := Values{"b": {"2"}, "a": {"1"}, "c": {}}.Environ()
+	if want := []string{"a=1", "b=2", "c="}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Environ() = %q, %v; want %q, nil", got, err, want)
+	}
+
+	if _, err := (Values{"a": {"1", "2"}}).Environ(); !errors.Is(err, ErrMultipleValues) {
+		t.Errorf("Environ() err = %v; want %v", err, ErrMultipleValues)
+	}
+}
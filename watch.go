@@ -0,0 +1,415 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWatchInterval is the default interval at which a Watcher polls files for changes.
+const DefaultWatchInterval = 5 * time.Second
+
+// Change describes a value that differs between two loads of a Watcher's value.
+type Change struct {
+	Field string      // The Go path of the value (e.g., "DB.Peers" or "Limits[foo]"), or "" for the whole value
+	Old   interface{} // The old value, or nil if it was a map entry that didn't exist
+	New   interface{} // The new value, or nil if it is a map entry that no longer exists
+}
+
+// Update is a value published by a Watcher, passed to its subscribers.
+type Update[T any] struct {
+	Old     *T       // The previously published value
+	New     *T       // The newly published value
+	Changes []Change // The fields that differ between Old and New, in field order
+}
+
+// Watcher reloads a value of type T, usually a struct, when the files it was loaded from change or
+// the process receives SIGHUP, and publishes each new value that differs from the last. Values are
+// loaded as by Parse, into a new T each time, so that variables that are removed revert to their
+// defaults.
+//
+// Files are polled, at the Watcher's Interval, by their modification times and sizes. The files
+// polled are those read through file variables (see Reader.FileSuffix), the directories of FSEnv
+// sources (including the layers of Layered sources, such as a Chain) and their files, and the
+// Watcher's Files.
+//
+// Values that fail to load or validate are not published. Since each load would reopen them, T
+// must not hold files, writers, listeners, or other io.Closers; if it does, loads fail with a
+// *ResourceError.
+//
+// A Watcher must not be copied after first use.
+type Watcher[T any] struct {
+	// Reader loads the Watcher's value. If Reader is nil, the DefaultReader is used.
+	Reader *Reader
+	// Key is the key or prefix of the Watcher's value.
+	Key string
+	// Interval is the interval at which files are polled for changes. If Interval is less than or
+	// equal to zero, it defaults to DefaultWatchInterval.
+	Interval time.Duration
+	// Files are paths of additional files or directories to poll for changes (e.g., dotenv files
+	// read by Source).
+	Files []string
+	// Source, if not nil, is called before each load to get the source to use in place of the
+	// Reader's Source (e.g., to read a dotenv file with ReadDotenv).
+	Source func() (Env, error)
+	// Validate, if not nil, is called with each value loaded. If it returns an error, the value is
+	// not published.
+	Validate func(*T) error
+	// OnError, if not nil, is called with the errors of reloads started by Run.
+	OnError func(error)
+
+	value atomic.Pointer[T]
+
+	mu    sync.Mutex // Held while reloading or subscribing
+	subs  []func(Update[T])
+	files []watchFile
+	stamp []fileStamp
+}
+
+// ResourceError is the error of a Watcher whose value type holds a resource (e.g., an *os.File,
+// io.Writer, or net.Listener) or any other io.Closer.
+type ResourceError struct {
+	Field string       // The Go path of the resource (e.g., "Log" or "Servers[]")
+	Type  reflect.Type // The resource's type
+}
+
+func (e *ResourceError) Error() string {
+	if e.Field == "" {
+		return "can't watch " + e.Type.String()
+	}
+	return "can't watch " + e.Type.String() + " field " + e.Field
+}
+
+// Value returns the Watcher's current value, or nil if no value has been published. The value
+// must not be modified.
+func (w *Watcher[T]) Value() *T {
+	return w.value.Load()
+}
+
+// Subscribe registers fn to be called with each value published after the first. Subscribers are
+// called in the order they subscribed, by the goroutine reloading the value, and must not call
+// Reload or Subscribe.
+func (w *Watcher[T]) Subscribe(fn func(Update[T])) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Reload loads and validates the Watcher's value and publishes it, if it differs from the current
+// value, notifying subscribers. If loading or validating the value fails, it returns that error
+// and the current value is kept.
+func (w *Watcher[T]) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reload()
+}
+
+// Run reloads the Watcher's value when its files change or the process receives SIGHUP, until ctx
+// is done, and returns ctx's error. Errors reloading the value are passed to OnError. If no value
+// has been published, Run first calls Reload and returns its error if it fails. On js, which has no
+// SIGHUP, Run only polls files.
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	if w.Value() == nil {
+		if err := w.Reload(); err != nil {
+			return err
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	notifyReload(hup)
+	defer signal.Stop(hup)
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hup:
+			err = w.Reload()
+		case <-tick.C:
+			err = w.poll()
+		}
+		if err != nil && w.OnError != nil {
+			w.OnError(err)
+		}
+	}
+}
+
+// poll reloads the Watcher's value if any of its files have changed since it was last loaded.
+func (w *Watcher[T]) poll() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if reflect.DeepEqual(stampFiles(w.files), w.stamp) {
+		return nil
+	}
+	return w.reload()
+}
+
+func (w *Watcher[T]) reload() error {
+	v, err := w.load()
+	if err != nil {
+		return err
+	}
+
+	old := w.value.Load()
+	var changes []Change
+	if old != nil {
+		r := readerOrDefault(w.Reader)
+		changes = r.diff(nil, "", reflect.ValueOf(old).Elem(), reflect.ValueOf(v).Elem())
+		if len(changes) == 0 {
+			return nil
+		}
+	}
+
+	w.value.Store(v)
+	if old != nil {
+		for _, fn := range w.subs {
+			fn(Update[T]{Old: old, New: v, Changes: changes})
+		}
+	}
+	return nil
+}
+
+// load loads and validates a new value, and records the files it was loaded from.
+func (w *Watcher[T]) load() (*T, error) {
+	r := *readerOrDefault(w.Reader)
+	if w.Source != nil {
+		src, err := w.Source()
+		if err != nil {
+			return nil, err
+		}
+		r.Source = src
+	}
+
+	v := new(T)
+	if err := checkResources("", reflect.TypeOf(v).Elem(), map[reflect.Type]bool{}); err != nil {
+		return nil, err
+	}
+	trace, err := r.GetenvTrace(v, w.Key)
+
+	w.files = w.files[:0]
+	for _, p := range w.Files {
+		w.files = append(w.files, watchFile{name: p})
+	}
+	w.files = appendSourceFiles(w.files, r.Source)
+	for _, e := range trace {
+		if e.File != "" {
+			w.files = append(w.files, watchFile{name: e.File})
+		}
+	}
+	// Files are stamped even if loading fails, so that an invalid value isn't reloaded until its
+	// files change again.
+	w.stamp = stampFiles(w.files)
+
+	if IsNoValue(err) {
+		err = nil
+	}
+	if err == nil && w.Validate != nil {
+		err = w.Validate(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// checkResources returns a *ResourceError if values of type t, at the Go path field, can hold an
+// io.Writer or io.Closer. Only exported struct fields are checked, since envi doesn't load
+// unexported ones.
+func checkResources(field string, t reflect.Type, seen map[reflect.Type]bool) error {
+	if isResourceType(t) {
+		return &ResourceError{Field: field, Type: t}
+	}
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return checkResources(field, t.Elem(), seen)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return checkResources(field+"[]", t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if field != "" {
+				name = field + "." + name
+			}
+			if err := checkResources(name, f.Type, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// watchFile is a file or directory polled by a Watcher. If fsys is nil, name is a path of the
+// operating system.
+type watchFile struct {
+	fsys fs.FS
+	name string
+}
+
+// fileStamp is the state of a polled file. If a directory is polled, its files are also stamped.
+type fileStamp struct {
+	name string
+	mod  time.Time
+	size int64
+	mode fs.FileMode
+	ok   bool
+}
+
+// appendSourceFiles appends the directories of the FSEnv sources in env to files.
+func appendSourceFiles(files []watchFile, env Env) []watchFile {
+	switch e := env.(type) {
+	case FSEnv:
+		files = append(files, watchFile{fsys: e.FS, name: e.dir()})
+	case *FSEnv:
+		files = append(files, watchFile{fsys: e.FS, name: e.dir()})
+	case Layered:
+		for _, l := range e.Layers() {
+			files = appendSourceFiles(files, l.Env)
+		}
+	}
+	return files
+}
+
+func stampFiles(files []watchFile) (stamps []fileStamp) {
+	for _, f := range files {
+		stamps = f.stamp(stamps)
+	}
+	return stamps
+}
+
+func (f watchFile) stat(name string) (fs.FileInfo, error) {
+	if f.fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(f.fsys, name)
+}
+
+func (f watchFile) join(name string) string {
+	if f.fsys == nil {
+		return filepath.Join(f.name, name)
+	}
+	return path.Join(f.name, name)
+}
+
+// stamp appends the stamps of f, and its files if it is a directory, to stamps.
+func (f watchFile) stamp(stamps []fileStamp) []fileStamp {
+	stampFile := func(name string) fs.FileInfo {
+		fi, err := f.stat(name)
+		if err != nil {
+			stamps = append(stamps, fileStamp{name: name})
+			return nil
+		}
+		stamps = append(stamps, fileStamp{name, fi.ModTime(), fi.Size(), fi.Mode(), true})
+		return fi
+	}
+
+	if fi := stampFile(f.name); fi == nil || !fi.IsDir() {
+		return stamps
+	}
+
+	var (
+		entries []fs.DirEntry
+		err     error
+	)
+	if f.fsys == nil {
+		entries, err = os.ReadDir(f.name)
+	} else {
+		entries, err = fs.ReadDir(f.fsys, f.name)
+	}
+	if err != nil {
+		return append(stamps, fileStamp{name: f.name})
+	}
+	for _, e := range entries {
+		stampFile(f.join(e.Name()))
+	}
+	return stamps
+}
+
+// diff appends the differences between the values a and b, at the Go path field, to changes and
+// returns the result. Structs, pointers to them, and maps are compared by their exported fields and
+// entries, and all other values, including those the Reader loads from a single value (such as
+// time.Time and types with Decoders), as a whole.
+func (r *Reader) diff(changes []Change, field string, a, b reflect.Value) []Change {
+	t := a.Type()
+	switch {
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && !r.isSplitType(t):
+		if !a.IsNil() && !b.IsNil() {
+			return r.diff(changes, field, a.Elem(), b.Elem())
+		}
+	case t.Kind() == reflect.Struct && !r.isSplitType(t):
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() {
+				name := f.Name
+				if field != "" {
+					name = field + "." + name
+				}
+				changes = r.diff(changes, name, a.Field(i), b.Field(i))
+			}
+		}
+		return changes
+	case t.Kind() == reflect.Map && !a.IsNil() && !b.IsNil() && !r.isSplitType(t):
+		return r.diffMap(changes, field, a, b)
+	}
+
+	if av, bv := a.Interface(), b.Interface(); !reflect.DeepEqual(av, bv) {
+		changes = append(changes, Change{Field: field, Old: av, New: bv})
+	}
+	return changes
+}
+
+func (r *Reader) diffMap(changes []Change, field string, a, b reflect.Value) []Change {
+	keys := map[string]reflect.Value{}
+	for _, m := range []reflect.Value{a, b} {
+		for iter := m.MapRange(); iter.Next(); {
+			keys[fmt.Sprint(iter.Key().Interface())] = iter.Key()
+		}
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		k := keys[name]
+		elem := field + "[" + name + "]"
+		av, bv := a.MapIndex(k), b.MapIndex(k)
+		switch {
+		case !av.IsValid():
+			changes = append(changes, Change{Field: elem, New: bv.Interface()})
+		case !bv.IsValid():
+			changes = append(changes, Change{Field: elem, Old: av.Interface()})
+		default:
+			changes = r.diff(changes, elem, av, bv)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,17 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+//go:build !js
+
+package envi
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReload relays the signals that reload a Watcher (SIGHUP) to c.
+func notifyReload(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGHUP)
+}
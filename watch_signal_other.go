@@ -0,0 +1,11 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+//go:build js
+
+package envi
+
+import "os"
+
+// notifyReload does nothing, since there is no SIGHUP on this platform. Watchers only poll files.
+func notifyReload(c chan<- os.Signal) {}
@@ -0,0 +1,258 @@
+// Copyright 2015 Kochava. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found in LICENSE.txt.
+
+package envi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type watchConfig struct {
+	Level  string `envi:",default=info"`
+	Rate   int
+	Limits map[string]int
+	DB     *watchDB
+}
+
+type watchDB struct {
+	Host string
+}
+
+var errWatchRate = errors.New("rate must not be negative")
+
+func validateWatchConfig(c *watchConfig) error {
+	if c.Rate < 0 {
+		return errWatchRate
+	}
+	return nil
+}
+
+func writeWatchFile(t *testing.T, dir, name, val string) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(val+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Move the modification time forward, in case the file system's timestamps are coarse.
+	mod := time.Now().Add(time.Duration(len(val)+1) * time.Minute)
+	if err := os.Chtimes(p, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	dir := t.TempDir()
+	writeWatchFile(t, dir, "APP_Rate", "10")
+	writeWatchFile(t, dir, "APP_Limits_a", "1")
+
+	w := &Watcher[watchConfig]{
+		Reader:   &Reader{Source: DirEnv(dir), Sep: "_"},
+		Key:      "APP",
+		Validate: validateWatchConfig,
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	first := w.Value()
+	if want := (&watchConfig{Level: "info", Rate: 10, Limits: map[string]int{"a": 1}}); !reflect.DeepEqual(first, want) {
+		t.Fatalf("Value() = %#+v; want %#+v", first, want)
+	}
+
+	var updates []Update[watchConfig]
+	w.Subscribe(func(u Update[watchConfig]) { updates = append(updates, u) })
+
+	// Unchanged values are not published.
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	} else if w.Value() != first || len(updates) != 0 {
+		t.Fatalf("Reload() published an unchanged value")
+	}
+
+	writeWatchFile(t, dir, "APP_Level", "debug")
+	writeWatchFile(t, dir, "APP_Limits_b", "2")
+	writeWatchFile(t, dir, "APP_DB_Host", "db1")
+	os.Remove(filepath.Join(dir, "APP_Limits_a"))
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	want := []Change{
+		{Field: "Level", Old: "info", New: "debug"},
+		{Field: "Limits[a]", Old: 1},
+		{Field: "Limits[b]", New: 2},
+		{Field: "DB", Old: (*watchDB)(nil), New: &watchDB{Host: "db1"}},
+	}
+	if len(updates) != 1 {
+		t.Fatalf("Reload() published %d updates; want 1", len(updates))
+	} else if u := updates[0]; u.Old != first || u.New != w.Value() || !reflect.DeepEqual(u.Changes, want) {
+		t.Fatalf("Reload() update = %#+v; want changes %#+v", u, want)
+	}
+
+	for _, bad := range []string{"fast", "-1"} {
+		writeWatchFile(t, dir, "APP_Rate", bad)
+		cur := w.Value()
+		if err := w.Reload(); err == nil {
+			t.Errorf("Reload() with Rate=%s error = nil; want an error", bad)
+		} else if bad == "-1" && err != errWatchRate {
+			t.Errorf("Reload() with Rate=%s error = %v; want %v", bad, err, errWatchRate)
+		}
+		if w.Value() != cur || len(updates) != 1 {
+			t.Errorf("Reload() with Rate=%s published a value", bad)
+		}
+	}
+}
+
+type watchPoint struct {
+	X, Y int
+}
+
+func TestWatcherReloadDecoders(t *testing.T) {
+	type config struct {
+		Point watchPoint
+		Tags  map[string]bool
+	}
+
+	env := Values{"APP_Point": {"1,2"}, "APP_Tags": {"a"}}
+	r := &Reader{Source: env, Sep: "_"}
+	Register(r, func(key, val string) (p watchPoint, err error) {
+		_, err = fmt.Sscanf(val, "%d,%d", &p.X, &p.Y)
+		return p, err
+	})
+	Register(r, func(key, val string) (map[string]bool, error) {
+		tags := map[string]bool{}
+		for _, tag := range strings.Split(val, ",") {
+			tags[tag] = true
+		}
+		return tags, nil
+	})
+
+	w := &Watcher[config]{Reader: r, Key: "APP"}
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	var updates []Update[config]
+	w.Subscribe(func(u Update[config]) { updates = append(updates, u) })
+
+	env["APP_Point"], env["APP_Tags"] = []string{"1,3"}, []string{"a,b"}
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	want := []Change{
+		{Field: "Point", Old: watchPoint{1, 2}, New: watchPoint{1, 3}},
+		{Field: "Tags", Old: map[string]bool{"a": true}, New: map[string]bool{"a": true, "b": true}},
+	}
+	if len(updates) != 1 || !reflect.DeepEqual(updates[0].Changes, want) {
+		t.Fatalf("Reload() updates = %#+v; want changes %#+v", updates, want)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	writeWatchFile(t, dir, "secret", "old")
+
+	w := &Watcher[watchConfig]{
+		Reader:   &Reader{Source: Values{"APP_Level_FILE": {secret}}, Sep: "_", FileSuffix: "_FILE"},
+		Key:      "APP",
+		Interval: time.Millisecond,
+		Validate: validateWatchConfig,
+	}
+	updates := make(chan Update[watchConfig], 1)
+	w.Subscribe(func(u Update[watchConfig]) { updates <- u })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("Run() error = %v; want %v", err, context.Canceled)
+		}
+	}()
+
+	for w.Value() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	if got := w.Value().Level; got != "old" {
+		t.Fatalf("Value().Level = %q; want %q", got, "old")
+	}
+
+	writeWatchFile(t, dir, "secret", "new")
+	select {
+	case u := <-updates:
+		want := []Change{{Field: "Level", Old: "old", New: "new"}}
+		if !reflect.DeepEqual(u.Changes, want) {
+			t.Errorf("Run() update changes = %#+v; want %#+v", u.Changes, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not publish an update after the file changed")
+	}
+}
+
+func TestWatcherRunError(t *testing.T) {
+	w := &Watcher[watchConfig]{
+		Reader: &Reader{Source: Values{"APP_Rate": {"fast"}}, Sep: "_"},
+		Key:    "APP",
+	}
+	if err := w.Run(context.Background()); err == nil {
+		t.Fatal("Run() error = nil; want an error")
+	}
+	if w.Value() != nil {
+		t.Errorf("Value() = %#+v; want nil", w.Value())
+	}
+}
+
+func watchResourceError[T any](t *testing.T) *ResourceError {
+	t.Helper()
+	dir := t.TempDir()
+	w := &Watcher[T]{
+		Reader: &Reader{Source: Values{"APP_Out": {filepath.Join(dir, "out")}}, Sep: "_"},
+		Key:    "APP",
+	}
+	err := w.Reload()
+	var re *ResourceError
+	if !errors.As(err, &re) {
+		t.Fatalf("Reload() error = %v; want a *ResourceError", err)
+	}
+	if w.Value() != nil {
+		t.Errorf("Value() = %#+v; want nil", w.Value())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out")); err == nil {
+		t.Errorf("Reload() opened a file")
+	}
+	return re
+}
+
+func TestWatcherResources(t *testing.T) {
+	type server struct {
+		Addr string
+		Ln   net.Listener
+	}
+
+	cases := []struct {
+		err   *ResourceError
+		field string
+		typ   reflect.Type
+	}{
+		{watchResourceError[struct{ Out *os.File }](t), "Out", reflect.TypeOf((*os.File)(nil))},
+		{watchResourceError[struct{ Out io.Writer }](t), "Out", reflect.TypeOf((*io.Writer)(nil)).Elem()},
+		{watchResourceError[struct {
+			Level   string
+			Servers map[string]*server
+		}](t), "Servers[].Ln", reflect.TypeOf((*net.Listener)(nil)).Elem()},
+	}
+	for _, c := range cases {
+		if c.err.Field != c.field || c.err.Type != c.typ {
+			t.Errorf("Reload() error = %v; want field %s of type %v", c.err, c.field, c.typ)
+		}
+	}
+}